@@ -0,0 +1,169 @@
+// Package daemon implements the execution of the daemon command. It replaces
+// the execution of the microkit daemon command, which stops the server
+// immediately on SIGTERM, so that our server is able to keep serving defer
+// queries until the POD lifecycle allows it to shut down.
+package daemon
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/giantswarm/microerror"
+	microdaemonflag "github.com/giantswarm/microkit/command/daemon/flag"
+	microflag "github.com/giantswarm/microkit/flag"
+	microserver "github.com/giantswarm/microkit/server"
+	"github.com/giantswarm/micrologger"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var (
+	f = microdaemonflag.New()
+)
+
+// ServerFactory creates the custom server once all command line flags are
+// parsed. Other than the microkit server factory it returns an error, so that
+// startup failures are logged instead of panicking.
+type ServerFactory func(v *viper.Viper) (microserver.Server, error)
+
+// Starter is implemented by custom servers which start additional listeners,
+// e.g. on a Unix domain socket. Other than Boot, Start returns an error, so
+// that the daemon fails when a configured listener cannot be started.
+type Starter interface {
+	Start() error
+}
+
+// Config represents the configuration used to create a new daemon command.
+type Config struct {
+	Logger        micrologger.Logger
+	ServerFactory ServerFactory
+	// Viper must be the viper the microkit daemon command is configured
+	// with, so that its flags are available.
+	Viper *viper.Viper
+}
+
+type Command struct {
+	logger        micrologger.Logger
+	serverFactory ServerFactory
+	viper         *viper.Viper
+}
+
+// New creates a new daemon command.
+func New(config Config) (*Command, error) {
+	if config.Logger == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Logger must not be empty", config)
+	}
+	if config.ServerFactory == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.ServerFactory must not be empty", config)
+	}
+	if config.Viper == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Viper must not be empty", config)
+	}
+
+	c := &Command{
+		logger:        config.Logger,
+		serverFactory: config.ServerFactory,
+		viper:         config.Viper,
+	}
+
+	return c, nil
+}
+
+// Execute is meant to be used as Run function of the microkit daemon cobra
+// command. It boots the server like microkit does. On SIGTERM or SIGINT the
+// custom server is shut down first, which blocks until the server is ready to
+// stop serving, before the microkit server is shut down gracefully. A second
+// signal exits immediately. Startup failures, including listeners of the
+// custom server which cannot be started, are logged and exit with a non zero
+// code.
+func (c *Command) Execute(cmd *cobra.Command, args []string) {
+	ctx := context.Background()
+
+	err := c.execute(ctx, cmd)
+	if err != nil {
+		_ = c.logger.LogCtx(ctx, "level", "error", "message", "failed to start daemon", "stack", fmt.Sprintf("%#v", err))
+		os.Exit(1)
+	}
+}
+
+func (c *Command) execute(ctx context.Context, cmd *cobra.Command) error {
+	// We have to parse the flags given via command line first. Only that way we
+	// are able to use the flag configuration for the location of configuration
+	// directories and files in the next step below.
+	microflag.Parse(c.viper, cmd.Flags())
+
+	err := microflag.Merge(c.viper, cmd.Flags(), c.viper.GetStringSlice(f.Config.Dirs), c.viper.GetStringSlice(f.Config.Files))
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	customServer, err := c.serverFactory(c.viper)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	var newServer microserver.Server
+	{
+		serverConfig := customServer.Config()
+
+		serverConfig.EnableDebugServer = c.viper.GetBool(f.Server.Enable.Debug.Server)
+		serverConfig.LogAccess = c.viper.GetBool(f.Server.Log.Access)
+		if serverConfig.ListenAddress == "" {
+			serverConfig.ListenAddress = c.viper.GetString(f.Server.Listen.Address)
+		}
+		if serverConfig.ListenMetricsAddress == "" {
+			serverConfig.ListenMetricsAddress = c.viper.GetString(f.Server.Listen.MetricsAddress)
+		}
+		if serverConfig.TLSCAFile == "" {
+			serverConfig.TLSCAFile = c.viper.GetString(f.Server.TLS.CaFile)
+		}
+		if serverConfig.TLSCrtFile == "" {
+			serverConfig.TLSCrtFile = c.viper.GetString(f.Server.TLS.CrtFile)
+		}
+		if serverConfig.TLSKeyFile == "" {
+			serverConfig.TLSKeyFile = c.viper.GetString(f.Server.TLS.KeyFile)
+		}
+
+		newServer, err = microserver.New(serverConfig)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+
+		// The microkit server registers all endpoints to the router when
+		// booting, so it is booted first. The custom server may serve the
+		// router on additional listeners.
+		newServer.Boot()
+
+		starter, ok := customServer.(Starter)
+		if ok {
+			err = starter.Start()
+			if err != nil {
+				return microerror.Mask(err)
+			}
+		} else {
+			go customServer.Boot()
+		}
+	}
+
+	signals := make(chan os.Signal, 2)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+
+	s := <-signals
+	_ = c.logger.LogCtx(ctx, "level", "debug", "message", fmt.Sprintf("received %s, shutting down server", s))
+
+	go func() {
+		customServer.Shutdown()
+		newServer.Shutdown()
+
+		_ = c.logger.LogCtx(ctx, "level", "debug", "message", "shut down server")
+		os.Exit(0)
+	}()
+
+	s = <-signals
+	_ = c.logger.LogCtx(ctx, "level", "debug", "message", fmt.Sprintf("received %s, exiting immediately", s))
+
+	return nil
+}
@@ -0,0 +1,240 @@
+// Package hook implements the hook command, which is meant to be executed as
+// Kubernetes preStop hook. It polls the shutdown-deferrer defer endpoint until
+// node termination does not have to be deferred anymore.
+package hook
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/giantswarm/microerror"
+	"github.com/giantswarm/micrologger"
+	"github.com/spf13/cobra"
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+const (
+	// ExitCodeAllowed is returned when node termination does not have to be
+	// deferred anymore.
+	ExitCodeAllowed = 0
+	// ExitCodeFailure is returned when the command is used wrongly.
+	ExitCodeFailure = 1
+	// ExitCodeTimeout is returned when the timeout elapsed while node
+	// termination was still deferred.
+	ExitCodeTimeout = 2
+	// ExitCodeInterrupted is returned when the command received SIGINT or
+	// SIGTERM while node termination was still deferred.
+	ExitCodeInterrupted = 3
+)
+
+const (
+	// jitterFactor is the maximum fraction added to the poll interval in order
+	// to spread requests of many hooks polling at the same time.
+	jitterFactor = 0.2
+	// maxBackoffFactor limits the exponential backoff applied to the poll
+	// interval while requests to the defer endpoint fail.
+	maxBackoffFactor = 8
+)
+
+const (
+	flagExitDelay = "exit-delay"
+	flagInterval  = "interval"
+	flagTimeout   = "timeout"
+	flagTokenFile = "token-file"
+	flagURL       = "url"
+)
+
+// Config represents the configuration used to create a new hook command.
+type Config struct {
+	Logger micrologger.Logger
+}
+
+type Command struct {
+	logger micrologger.Logger
+
+	cobraCommand *cobra.Command
+	httpClient   *http.Client
+	// tokenFile contains the bearer token sent with defer queries, if any.
+	tokenFile string
+}
+
+// New creates a new hook command.
+func New(config Config) (*Command, error) {
+	if config.Logger == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Logger must not be empty", config)
+	}
+
+	c := &Command{
+		logger: config.Logger,
+
+		httpClient: &http.Client{},
+	}
+
+	c.cobraCommand = &cobra.Command{
+		Use:   "hook",
+		Short: "Wait until node termination does not have to be deferred anymore.",
+		Long:  "Poll the shutdown-deferrer defer endpoint until node termination does not have to be deferred anymore. Meant to be executed as preStop hook.",
+		Args:  cobra.MaximumNArgs(1),
+		Run:   c.Execute,
+	}
+
+	c.cobraCommand.Flags().Duration(flagExitDelay, 0, "Time to wait before exiting so that other preStop hooks have the chance to do their last defer query. Not needed when the daemon is configured with a drain period, which keeps it serving after SIGTERM.")
+	c.cobraCommand.Flags().Duration(flagInterval, 5*time.Second, "Interval in which the defer endpoint is polled.")
+	c.cobraCommand.Flags().Duration(flagTimeout, 120*time.Second, "Maximum time to wait for node termination to not be deferred anymore.")
+	c.cobraCommand.Flags().String(flagTokenFile, "", "File containing the bearer token sent with defer queries when the daemon requires authentication, e.g. a projected service account token.")
+	c.cobraCommand.Flags().String(flagURL, "", "URL of the shutdown-deferrer defer endpoint, e.g. http://127.0.0.1:60080/v1/defer/. Can also be given as argument.")
+
+	return c, nil
+}
+
+func (c *Command) CobraCommand() *cobra.Command {
+	return c.cobraCommand
+}
+
+func (c *Command) Execute(cmd *cobra.Command, args []string) {
+	os.Exit(c.execute(cmd, args))
+}
+
+func (c *Command) execute(cmd *cobra.Command, args []string) int {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	url, _ := cmd.Flags().GetString(flagURL)
+	if url == "" && len(args) == 1 {
+		url = args[0]
+	}
+	if url == "" {
+		_ = c.logger.LogCtx(ctx, "level", "error", "message", fmt.Sprintf("--%s must not be empty", flagURL))
+		return ExitCodeFailure
+	}
+
+	exitDelay, _ := cmd.Flags().GetDuration(flagExitDelay)
+	interval, _ := cmd.Flags().GetDuration(flagInterval)
+	timeout, _ := cmd.Flags().GetDuration(flagTimeout)
+	c.tokenFile, _ = cmd.Flags().GetString(flagTokenFile)
+	if interval <= 0 {
+		_ = c.logger.LogCtx(ctx, "level", "error", "message", fmt.Sprintf("--%s must be greater than 0", flagInterval))
+		return ExitCodeFailure
+	}
+
+	// Stop polling as soon as we are asked to terminate.
+	go func() {
+		signals := make(chan os.Signal, 1)
+		signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+
+		select {
+		case s := <-signals:
+			_ = c.logger.LogCtx(ctx, "level", "debug", "message", fmt.Sprintf("received %s", s))
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	code := c.Poll(ctx, url, interval, timeout)
+
+	// Other preStop hooks, e.g. the one of k8s-kvm, poll the defer endpoint as
+	// well. We give them the chance to do their last query before the
+	// shutdown-deferrer container is terminated along with the pod.
+	if code != ExitCodeInterrupted && exitDelay > 0 {
+		_ = c.logger.LogCtx(ctx, "level", "debug", "message", fmt.Sprintf("waiting %s before exiting", exitDelay))
+
+		select {
+		case <-time.After(exitDelay):
+		case <-ctx.Done():
+		}
+	}
+
+	return code
+}
+
+// Poll requests the defer endpoint at the given URL in the given interval until
+// node termination does not have to be deferred anymore, the timeout elapsed or
+// the context is done. The returned exit code reflects which of these
+// happened. Failed requests are retried with an exponential backoff.
+func (c *Command) Poll(ctx context.Context, url string, interval, timeout time.Duration) int {
+	deadline := time.After(timeout)
+
+	var failures int
+	for {
+		backoff := interval
+		for i := 0; i < failures && backoff < maxBackoffFactor*interval; i++ {
+			backoff *= 2
+		}
+
+		select {
+		case <-time.After(wait.Jitter(backoff, jitterFactor)):
+		case <-deadline:
+			_ = c.logger.LogCtx(ctx, "level", "warning", "message", fmt.Sprintf("stopped waiting for node termination to not be deferred after %s", timeout))
+			return ExitCodeTimeout
+		case <-ctx.Done():
+			_ = c.logger.LogCtx(ctx, "level", "warning", "message", "stopped waiting for node termination to not be deferred because of termination signal")
+			return ExitCodeInterrupted
+		}
+
+		shouldDefer, err := c.shouldDefer(ctx, url, interval)
+		if err != nil {
+			failures++
+			_ = c.logger.LogCtx(ctx, "level", "warning", "message", fmt.Sprintf("failed to request %s", url), "stack", fmt.Sprintf("%#v", err))
+			continue
+		}
+		failures = 0
+
+		_ = c.logger.LogCtx(ctx, "level", "debug", "message", fmt.Sprintf("GET %s: %t", url, shouldDefer))
+
+		if !shouldDefer {
+			return ExitCodeAllowed
+		}
+	}
+}
+
+func (c *Command) shouldDefer(ctx context.Context, url string, timeout time.Duration) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return false, microerror.Mask(err)
+	}
+
+	// The token is read for every request, because projected service account
+	// tokens are rotated.
+	if c.tokenFile != "" {
+		token, err := ioutil.ReadFile(c.tokenFile)
+		if err != nil {
+			return false, microerror.Mask(err)
+		}
+
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", strings.TrimSpace(string(token))))
+	}
+
+	res, err := c.httpClient.Do(req.WithContext(ctx))
+	if err != nil {
+		return false, microerror.Mask(err)
+	}
+	defer res.Body.Close()
+
+	b, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return false, microerror.Mask(err)
+	}
+
+	if res.StatusCode != http.StatusOK {
+		return false, microerror.Maskf(unexpectedResponseError, "expected status code %d, got %d", http.StatusOK, res.StatusCode)
+	}
+
+	switch body := strings.TrimSpace(string(b)); body {
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	default:
+		return false, microerror.Maskf(unexpectedResponseError, "expected response body true or false, got %q", body)
+	}
+}
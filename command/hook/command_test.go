@@ -0,0 +1,89 @@
+package hook
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/giantswarm/micrologger/microloggertest"
+)
+
+func Test_Poll(t *testing.T) {
+	testCases := []struct {
+		name             string
+		responses        []string
+		statusCode       int
+		cancel           bool
+		expectedExitCode int
+	}{
+		{
+			name:             "case 0: allowed once endpoint returns false",
+			responses:        []string{"true", "true", "false"},
+			statusCode:       http.StatusOK,
+			expectedExitCode: ExitCodeAllowed,
+		},
+		{
+			name:             "case 1: timeout while endpoint returns true",
+			responses:        []string{"true"},
+			statusCode:       http.StatusOK,
+			expectedExitCode: ExitCodeTimeout,
+		},
+		{
+			name:             "case 2: timeout while endpoint fails",
+			responses:        []string{"false"},
+			statusCode:       http.StatusInternalServerError,
+			expectedExitCode: ExitCodeTimeout,
+		},
+		{
+			name:             "case 3: interrupted when context is done",
+			responses:        []string{"true"},
+			statusCode:       http.StatusOK,
+			cancel:           true,
+			expectedExitCode: ExitCodeInterrupted,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var mutex sync.Mutex
+			var requests int
+
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				mutex.Lock()
+				defer mutex.Unlock()
+
+				i := requests
+				if i >= len(tc.responses) {
+					i = len(tc.responses) - 1
+				}
+				requests++
+
+				w.WriteHeader(tc.statusCode)
+				_, _ = w.Write([]byte(tc.responses[i]))
+			}))
+			defer server.Close()
+
+			c, err := New(Config{
+				Logger: microloggertest.New(),
+			})
+			if err != nil {
+				t.Fatalf("error == %#v, want nil", err)
+			}
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			if tc.cancel {
+				cancel()
+			}
+
+			exitCode := c.Poll(ctx, server.URL, 10*time.Millisecond, 500*time.Millisecond)
+			if exitCode != tc.expectedExitCode {
+				t.Fatalf("Poll() == %d, want %d", exitCode, tc.expectedExitCode)
+			}
+		})
+	}
+}
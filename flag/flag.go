@@ -3,11 +3,13 @@ package flag
 import (
 	"github.com/giantswarm/microkit/flag"
 
+	"github.com/giantswarm/shutdown-deferrer/flag/server"
 	"github.com/giantswarm/shutdown-deferrer/flag/service"
 )
 
 // Flag provides data structure for service command line flags.
 type Flag struct {
+	Server  server.Server
 	Service service.Service
 }
 
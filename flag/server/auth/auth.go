@@ -0,0 +1,14 @@
+package auth
+
+import (
+	"github.com/giantswarm/shutdown-deferrer/flag/server/auth/mtls"
+	"github.com/giantswarm/shutdown-deferrer/flag/server/auth/tokenreview"
+)
+
+// Auth is a data structure to hold command line configuration flags defining
+// how defer queries are authenticated.
+type Auth struct {
+	MTLS        mtls.MTLS
+	TokenFile   string
+	TokenReview tokenreview.TokenReview
+}
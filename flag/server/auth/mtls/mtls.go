@@ -0,0 +1,10 @@
+package mtls
+
+// MTLS is a data structure to hold command line configuration flags defining
+// the TLS listener authenticating clients by certificate.
+type MTLS struct {
+	Address string
+	CAFile  string
+	CrtFile string
+	KeyFile string
+}
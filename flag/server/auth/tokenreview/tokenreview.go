@@ -0,0 +1,9 @@
+package tokenreview
+
+// TokenReview is a data structure to hold command line configuration flags
+// defining the authentication of service account tokens using the Kubernetes
+// TokenReview API.
+type TokenReview struct {
+	Audiences string
+	Enabled   string
+}
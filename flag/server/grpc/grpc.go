@@ -0,0 +1,7 @@
+package grpc
+
+// GRPC is a data structure to hold command line configuration flags of the
+// gRPC server.
+type GRPC struct {
+	Address string
+}
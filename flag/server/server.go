@@ -0,0 +1,15 @@
+package server
+
+import (
+	"github.com/giantswarm/shutdown-deferrer/flag/server/auth"
+	"github.com/giantswarm/shutdown-deferrer/flag/server/grpc"
+	"github.com/giantswarm/shutdown-deferrer/flag/server/shutdown"
+)
+
+// Server is an intermediate data structure for command line configuration
+// flags of the server.
+type Server struct {
+	Auth     auth.Auth
+	GRPC     grpc.GRPC
+	Shutdown shutdown.Shutdown
+}
@@ -0,0 +1,7 @@
+package shutdown
+
+// Shutdown is a data structure to hold command line configuration flags
+// defining how the server shuts down.
+type Shutdown struct {
+	DrainPeriod string
+}
@@ -0,0 +1,9 @@
+package audit
+
+// Audit is a data structure to hold command line configuration flags
+// defining where the audit trail of defer queries is written to.
+type Audit struct {
+	MaxBackups string
+	MaxSize    string
+	Path       string
+}
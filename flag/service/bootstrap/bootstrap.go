@@ -0,0 +1,7 @@
+package bootstrap
+
+// Bootstrap is a data structure to hold command line configuration flags
+// defining how the Kubernetes clients are bootstrapped at startup.
+type Bootstrap struct {
+	Timeout string
+}
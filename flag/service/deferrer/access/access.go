@@ -0,0 +1,9 @@
+package access
+
+// Access is a data structure to hold command line configuration flags
+// defining how the Kubernetes API permissions required by the providers are
+// reviewed at startup.
+type Access struct {
+	Check    string
+	Required string
+}
@@ -0,0 +1,7 @@
+package annotations
+
+// Annotations is a data structure to hold command line configuration flags
+// defining which objects the force and max-defer annotations are read from.
+type Annotations struct {
+	Pod string
+}
@@ -0,0 +1,27 @@
+package deferrer
+
+import (
+	"github.com/giantswarm/shutdown-deferrer/flag/service/deferrer/access"
+	"github.com/giantswarm/shutdown-deferrer/flag/service/deferrer/annotations"
+	"github.com/giantswarm/shutdown-deferrer/flag/service/deferrer/drainerconfig"
+	"github.com/giantswarm/shutdown-deferrer/flag/service/deferrer/events"
+	"github.com/giantswarm/shutdown-deferrer/flag/service/deferrer/failure"
+	"github.com/giantswarm/shutdown-deferrer/flag/service/deferrer/override"
+	"github.com/giantswarm/shutdown-deferrer/flag/service/deferrer/pod"
+	"github.com/giantswarm/shutdown-deferrer/flag/service/deferrer/provider"
+)
+
+// Deferrer is a data structure to hold deferrer specific command line
+// configuration flags.
+type Deferrer struct {
+	Access        access.Access
+	Annotations   annotations.Annotations
+	Deadline      string
+	DrainerConfig drainerconfig.DrainerConfig
+	Events        events.Events
+	Failure       failure.Failure
+	MultiTenant   string
+	Override      override.Override
+	Pod           pod.Pod
+	Provider      provider.Provider
+}
@@ -0,0 +1,9 @@
+package drainerconfig
+
+// DrainerConfig is a data structure to hold command line configuration flags
+// overriding which DrainerConfig is looked up.
+type DrainerConfig struct {
+	LabelSelector string
+	Name          string
+	Namespace     string
+}
@@ -0,0 +1,8 @@
+package events
+
+// Events is a data structure to hold command line configuration flags
+// defining how Kubernetes Events about decision transitions are recorded.
+type Events struct {
+	DrainerConfig string
+	Enabled       string
+}
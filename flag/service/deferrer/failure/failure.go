@@ -0,0 +1,8 @@
+package failure
+
+// Failure is a data structure to hold command line configuration flags
+// defining how DrainerConfig lookup failures are handled.
+type Failure struct {
+	Policy    string
+	Threshold string
+}
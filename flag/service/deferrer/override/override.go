@@ -0,0 +1,7 @@
+package override
+
+// Override is a data structure to hold command line configuration flags
+// defining where overrides set using the override API are persisted.
+type Override struct {
+	Target string
+}
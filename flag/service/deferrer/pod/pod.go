@@ -0,0 +1,12 @@
+package pod
+
+// Pod is a data structure to hold command line configuration flags defining
+// how the name and namespace of the POD the deferrer is running in are
+// resolved.
+type Pod struct {
+	DownwardAPIDir string
+	Name           string
+	Namespace      string
+	NamespaceFile  string
+	Sources        string
+}
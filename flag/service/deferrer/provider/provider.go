@@ -0,0 +1,9 @@
+package provider
+
+// Provider is a data structure to hold command line configuration flags
+// defining which defer-condition providers are used and how their decisions
+// are combined.
+type Provider struct {
+	Combination string
+	Names       string
+}
@@ -1,8 +1,19 @@
 package service
 
-import "github.com/giantswarm/operatorkit/flag/service/kubernetes"
+import (
+	"github.com/giantswarm/operatorkit/flag/service/kubernetes"
+
+	"github.com/giantswarm/shutdown-deferrer/flag/service/audit"
+	"github.com/giantswarm/shutdown-deferrer/flag/service/bootstrap"
+	"github.com/giantswarm/shutdown-deferrer/flag/service/deferrer"
+	"github.com/giantswarm/shutdown-deferrer/flag/service/socket"
+)
 
 // Service is an intermediate data structure for command line configuration flags.
 type Service struct {
+	Audit      audit.Audit
+	Bootstrap  bootstrap.Bootstrap
+	Deferrer   deferrer.Deferrer
 	Kubernetes kubernetes.Kubernetes
+	Socket     socket.Socket
 }
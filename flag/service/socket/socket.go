@@ -0,0 +1,8 @@
+package socket
+
+// Socket is a data structure to hold command line configuration flags
+// defining the Unix domain socket the endpoints are served on.
+type Socket struct {
+	Mode string
+	Path string
+}
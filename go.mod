@@ -15,13 +15,16 @@ require (
 	github.com/giantswarm/operatorkit v0.0.0-20191209140411-5d098618662e
 	github.com/giantswarm/versionbundle v0.0.0-20191206123034-be95231628ae // indirect
 	github.com/go-kit/kit v0.9.0
+	github.com/golang/protobuf v1.3.2
 	github.com/googleapis/gnostic v0.3.1 // indirect
-	github.com/gorilla/mux v1.7.3 // indirect
+	github.com/gorilla/mux v1.7.3
 	github.com/imdario/mergo v0.3.8 // indirect
 	github.com/juju/errgo v0.0.0-20140925100237-08cceb5d0b53 // indirect
 	github.com/pelletier/go-toml v1.6.0 // indirect
+	github.com/prometheus/client_golang v1.0.0
 	github.com/prometheus/common v0.7.0 // indirect
 	github.com/prometheus/procfs v0.0.8 // indirect
+	github.com/spf13/cobra v0.0.5
 	github.com/spf13/jwalterweatherman v1.1.0 // indirect
 	github.com/spf13/viper v1.6.1
 	golang.org/x/net v0.0.0-20191209160850-c0dbc17a3553 // indirect
@@ -29,6 +32,8 @@ require (
 	golang.org/x/sys v0.0.0-20191206220618-eeba5f6aabab // indirect
 	golang.org/x/time v0.0.0-20191024005414-555d28b269f0 // indirect
 	google.golang.org/appengine v1.6.5 // indirect
+	google.golang.org/grpc v1.26.0
+	k8s.io/api v0.18.5
 	k8s.io/apiextensions-apiserver v0.18.5 // indirect
 	k8s.io/apimachinery v0.18.5
 	k8s.io/client-go v11.0.0+incompatible
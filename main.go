@@ -2,6 +2,8 @@ package main
 
 import (
 	"fmt"
+	"os"
+	"time"
 
 	"github.com/giantswarm/microerror"
 	"github.com/giantswarm/microkit/command"
@@ -9,10 +11,15 @@ import (
 	"github.com/giantswarm/micrologger"
 	"github.com/spf13/viper"
 
+	"github.com/giantswarm/shutdown-deferrer/command/daemon"
+	"github.com/giantswarm/shutdown-deferrer/command/hook"
 	"github.com/giantswarm/shutdown-deferrer/flag"
 	"github.com/giantswarm/shutdown-deferrer/pkg/project"
 	"github.com/giantswarm/shutdown-deferrer/server"
 	"github.com/giantswarm/shutdown-deferrer/service"
+	"github.com/giantswarm/shutdown-deferrer/service/audit"
+	"github.com/giantswarm/shutdown-deferrer/service/deferrer"
+	"github.com/giantswarm/shutdown-deferrer/service/identity"
 )
 
 var (
@@ -22,7 +29,8 @@ var (
 func main() {
 	err := mainWithError()
 	if err != nil {
-		panic(fmt.Sprintf("%#v\n", err))
+		fmt.Fprintf(os.Stderr, "%s\n", err)
+		os.Exit(1)
 	}
 }
 
@@ -36,9 +44,15 @@ func mainWithError() (err error) {
 		}
 	}
 
+	// The viper is shared by the microkit command and our daemon command
+	// replacing the execution of the microkit daemon command.
+	v := viper.New()
+
 	// Define server factory to create the custom server once all command line
 	// flags are parsed and all microservice configuration is processed.
-	newServerFactory := func(v *viper.Viper) microserver.Server {
+	newServerFactory := func(v *viper.Viper) (microserver.Server, error) {
+		var err error
+
 		// New custom service implements the business logic.
 		var newService *service.Service
 		{
@@ -56,7 +70,7 @@ func mainWithError() (err error) {
 
 			newService, err = service.New(serviceConfig)
 			if err != nil {
-				panic(fmt.Sprintf("%#v\n", microerror.Maskf(err, "service.New")))
+				return nil, microerror.Maskf(err, "service.New")
 			}
 
 			go newService.Boot()
@@ -70,16 +84,42 @@ func mainWithError() (err error) {
 				Service: newService,
 				Viper:   v,
 
+				Auth: server.AuthConfig{
+					MTLS: server.AuthConfigMTLS{
+						Address: v.GetString(f.Server.Auth.MTLS.Address),
+						CAFile:  v.GetString(f.Server.Auth.MTLS.CAFile),
+						CrtFile: v.GetString(f.Server.Auth.MTLS.CrtFile),
+						KeyFile: v.GetString(f.Server.Auth.MTLS.KeyFile),
+					},
+					TokenFile:            v.GetString(f.Server.Auth.TokenFile),
+					TokenReview:          v.GetBool(f.Server.Auth.TokenReview.Enabled),
+					TokenReviewAudiences: v.GetStringSlice(f.Server.Auth.TokenReview.Audiences),
+				},
+				DrainPeriod: v.GetDuration(f.Server.Shutdown.DrainPeriod),
+				GRPC: server.GRPCConfig{
+					Address: v.GetString(f.Server.GRPC.Address),
+				},
+				Socket: server.SocketConfig{
+					Mode: v.GetString(f.Service.Socket.Mode),
+					Path: v.GetString(f.Service.Socket.Path),
+				},
 				ProjectName: project.Name(),
 			}
 
 			newServer, err = server.New(c)
 			if err != nil {
-				panic(fmt.Sprintf("%#v\n", microerror.Maskf(err, "server.New")))
+				return nil, microerror.Maskf(err, "server.New")
 			}
 		}
 
-		return newServer
+		return newServer, nil
+	}
+
+	// The microkit command requires a server factory without error. It is never
+	// called, since the execution of the microkit daemon command is replaced by
+	// our daemon command below.
+	microkitServerFactory := func(v *viper.Viper) microserver.Server {
+		return nil
 	}
 
 	// Create a new microkit command that manages operator daemon.
@@ -87,7 +127,8 @@ func mainWithError() (err error) {
 	{
 		c := command.Config{
 			Logger:        newLogger,
-			ServerFactory: newServerFactory,
+			ServerFactory: microkitServerFactory,
+			Viper:         v,
 
 			Description: project.Description(),
 			GitCommit:   project.GitSHA(),
@@ -102,14 +143,84 @@ func mainWithError() (err error) {
 		}
 	}
 
-	daemonCommand := newCommand.DaemonCommand().CobraCommand()
+	// Replace the execution of the microkit daemon command so that the server
+	// keeps serving defer queries after SIGTERM until it is allowed to shut
+	// down.
+	var newDaemonCommand *daemon.Command
+	{
+		c := daemon.Config{
+			Logger:        newLogger,
+			ServerFactory: newServerFactory,
+			Viper:         v,
+		}
 
+		newDaemonCommand, err = daemon.New(c)
+		if err != nil {
+			return microerror.Maskf(err, "daemon.New")
+		}
+	}
+
+	daemonCommand := newCommand.DaemonCommand().CobraCommand()
+	daemonCommand.Run = newDaemonCommand.Execute
+
+	daemonCommand.PersistentFlags().String(f.Server.Auth.MTLS.Address, "", "Address of the additional TLS listener requiring client certificates, e.g. 0.0.0.0:8443.")
+	daemonCommand.PersistentFlags().String(f.Server.Auth.MTLS.CAFile, "", "CA file client certificates must be signed by. Enables client certificate authentication of defer queries.")
+	daemonCommand.PersistentFlags().String(f.Server.Auth.MTLS.CrtFile, "", "Certificate file of the TLS listener requiring client certificates.")
+	daemonCommand.PersistentFlags().String(f.Server.Auth.MTLS.KeyFile, "", "Key file of the TLS listener requiring client certificates.")
+	daemonCommand.PersistentFlags().String(f.Server.Auth.TokenFile, "", "File containing a shared bearer token. Enables bearer token authentication of defer queries.")
+	daemonCommand.PersistentFlags().StringSlice(f.Server.Auth.TokenReview.Audiences, nil, "Audiences service account tokens must be valid for. Defaults to the audience of the API server.")
+	daemonCommand.PersistentFlags().Bool(f.Server.Auth.TokenReview.Enabled, false, "Whether to authenticate defer queries presenting service account tokens using the Kubernetes TokenReview API.")
+	daemonCommand.PersistentFlags().String(f.Server.GRPC.Address, "", "Address of the gRPC server serving the Deferrer and health services, e.g. 0.0.0.0:8001. The gRPC server is disabled when empty.")
+	daemonCommand.PersistentFlags().Duration(f.Server.Shutdown.DrainPeriod, 15*time.Second, "Maximum time the server keeps serving after SIGTERM while waiting for the final decision allowing node termination.")
+
+	daemonCommand.PersistentFlags().Int(f.Service.Audit.MaxBackups, 3, "Number of rotated audit trail files kept.")
+	daemonCommand.PersistentFlags().Int64(f.Service.Audit.MaxSize, 10*1024*1024, "Size in bytes after which the audit trail file is rotated. Zero disables rotation.")
+	daemonCommand.PersistentFlags().String(f.Service.Audit.Path, "", fmt.Sprintf("File the audit trail of defer queries is written to as JSON lines. Use %q for stdout. Empty disables the audit trail.", audit.PathStdout))
+	daemonCommand.PersistentFlags().Duration(f.Service.Bootstrap.Timeout, 2*time.Minute, "Maximum time to wait for the Kubernetes API server to become reachable at startup. Zero disables waiting.")
+	daemonCommand.PersistentFlags().Bool(f.Service.Deferrer.Access.Check, true, "Whether to review the Kubernetes API permissions required by the providers using SelfSubjectAccessReviews at startup.")
+	daemonCommand.PersistentFlags().Bool(f.Service.Deferrer.Annotations.Pod, false, fmt.Sprintf("Whether to read the %s and %s annotations from the POD in addition to the DrainerConfig. Requires permission to get, list and watch PODs. Not supported in multi-tenant mode.", deferrer.AnnotationForce, deferrer.AnnotationMaxDefer))
+	daemonCommand.PersistentFlags().Bool(f.Service.Deferrer.Access.Required, false, "Whether to refuse to start when the service account is denied any Kubernetes API permission required by the providers or the review fails.")
+	daemonCommand.PersistentFlags().Duration(f.Service.Deferrer.Deadline, 0, fmt.Sprintf("Maximum time node termination is deferred. Zero disables the deadline. Can be overridden using the %s DrainerConfig annotation, or POD annotation when enabled.", deferrer.AnnotationMaxDefer))
+	daemonCommand.PersistentFlags().String(f.Service.Deferrer.DrainerConfig.LabelSelector, "", "Label selector matching the DrainerConfig to look up instead of the one named like the POD. Must match at most one DrainerConfig.")
+	daemonCommand.PersistentFlags().String(f.Service.Deferrer.DrainerConfig.Name, "", "Name of the DrainerConfig to look up. Defaults to the POD name.")
+	daemonCommand.PersistentFlags().String(f.Service.Deferrer.DrainerConfig.Namespace, "", "Namespace of the DrainerConfig to look up. Defaults to the POD namespace.")
+	daemonCommand.PersistentFlags().Bool(f.Service.Deferrer.Events.DrainerConfig, false, "Whether to also record Kubernetes Events on the DrainerConfig decisions are based on.")
+	daemonCommand.PersistentFlags().Bool(f.Service.Deferrer.Events.Enabled, false, "Whether to record Kubernetes Events on the POD when node termination is first deferred, when it is not deferred anymore and when lookups start failing. Requires permission to create events.")
+	daemonCommand.PersistentFlags().String(f.Service.Deferrer.Failure.Policy, deferrer.FailurePolicyDefer, fmt.Sprintf("Decision made when the DrainerConfig lookup fails. One of %s, %s or %s.", deferrer.FailurePolicyDefer, deferrer.FailurePolicyAllow, deferrer.FailurePolicyAllowAfterFailures))
+	daemonCommand.PersistentFlags().Int(f.Service.Deferrer.Failure.Threshold, 3, fmt.Sprintf("Consecutive DrainerConfig lookup failures after which node termination is allowed when using the %s failure policy. Failures are counted at most once every 5 seconds, regardless of the number of callers.", deferrer.FailurePolicyAllowAfterFailures))
+	daemonCommand.PersistentFlags().Bool(f.Service.Deferrer.MultiTenant, false, fmt.Sprintf("Whether to serve decisions for arbitrary PODs on /v1/defer/{namespace}/{pod} instead of for the POD the deferrer is running in. Only supports the %s provider.", deferrer.ProviderDrainerConfig))
+	daemonCommand.PersistentFlags().String(f.Service.Deferrer.Override.Target, "", fmt.Sprintf("Object the override set using the override API is persisted on as %s annotation. One of %s or %s. Empty disables the override API. Overrides are not supported in multi-tenant mode.", deferrer.AnnotationOverride, deferrer.OverrideTargetPod, deferrer.OverrideTargetDrainerConfig))
+	daemonCommand.PersistentFlags().String(f.Service.Deferrer.Pod.DownwardAPIDir, identity.DefaultDownwardAPIDir, fmt.Sprintf("Directory of the Downward API volume containing the name and namespace files of the POD, used by the %s source.", identity.SourceDownwardAPI))
+	daemonCommand.PersistentFlags().String(f.Service.Deferrer.Pod.Name, "", fmt.Sprintf("Name of the POD, used by the %s source.", identity.SourceFlag))
+	daemonCommand.PersistentFlags().String(f.Service.Deferrer.Pod.Namespace, "", fmt.Sprintf("Namespace of the POD, used by the %s source.", identity.SourceFlag))
+	daemonCommand.PersistentFlags().String(f.Service.Deferrer.Pod.NamespaceFile, identity.DefaultServiceAccountNamespaceFile, fmt.Sprintf("File containing the namespace of the POD, used by the %s source.", identity.SourceServiceAccount))
+	daemonCommand.PersistentFlags().StringSlice(f.Service.Deferrer.Pod.Sources, identity.DefaultSources, fmt.Sprintf("Sources asked in order for the name and namespace of the POD. Supported sources: %s, %s, %s, %s, %s. The %s source is only asked when configured explicitly.", identity.SourceFlag, identity.SourceEnv, identity.SourceDownwardAPI, identity.SourceServiceAccount, identity.SourceHostname, identity.SourceHostname))
+	daemonCommand.PersistentFlags().String(f.Service.Deferrer.Provider.Combination, deferrer.CombinationAll, fmt.Sprintf("How provider decisions are combined. With %s every provider must allow node termination, with %s a single provider may allow it.", deferrer.CombinationAll, deferrer.CombinationAny))
+	daemonCommand.PersistentFlags().StringSlice(f.Service.Deferrer.Provider.Names, []string{deferrer.ProviderDrainerConfig}, fmt.Sprintf("Providers deciding whether node termination has to be deferred. Supported providers: %s, %s, %s.", deferrer.ProviderDrainerConfig, deferrer.ProviderNodeDrain, deferrer.ProviderPodDisruptionBudget))
 	daemonCommand.PersistentFlags().String(f.Service.Kubernetes.Address, "", "Address used to connect to Kubernetes. When empty in-cluster config is created.")
 	daemonCommand.PersistentFlags().Bool(f.Service.Kubernetes.InCluster, true, "Whether to use the in-cluster config to authenticate with Kubernetes.")
 	daemonCommand.PersistentFlags().String(f.Service.Kubernetes.KubeConfig, "", "KubeConfig used to connect to Kubernetes. When empty other settings are used.")
 	daemonCommand.PersistentFlags().String(f.Service.Kubernetes.TLS.CAFile, "", "Certificate authority file path to use to authenticate with Kubernetes.")
 	daemonCommand.PersistentFlags().String(f.Service.Kubernetes.TLS.CrtFile, "", "Certificate file path to use to authenticate with Kubernetes.")
 	daemonCommand.PersistentFlags().String(f.Service.Kubernetes.TLS.KeyFile, "", "Key file path to use to authenticate with Kubernetes.")
+	daemonCommand.PersistentFlags().String(f.Service.Socket.Mode, "0660", "Octal file mode of the Unix domain socket.")
+	daemonCommand.PersistentFlags().String(f.Service.Socket.Path, "", "Path of the Unix domain socket additionally serving all endpoints, e.g. for node-local callers sharing it through a volume. Empty disables the socket.")
+
+	// Create the hook command that is executed as preStop hook and polls the
+	// defer endpoint of the daemon.
+	var hookCommand *hook.Command
+	{
+		c := hook.Config{
+			Logger: newLogger,
+		}
+
+		hookCommand, err = hook.New(c)
+		if err != nil {
+			return microerror.Maskf(err, "hook.New")
+		}
+	}
+
+	newCommand.CobraCommand().AddCommand(hookCommand.CobraCommand())
 
 	err = newCommand.CobraCommand().Execute()
 	if err != nil {
@@ -0,0 +1,11 @@
+// Package deferrerpb implements the messages, client and server of the
+// Deferrer gRPC service defined in deferrer.proto. The code in deferrer.pb.go
+// is generated, run go generate after changing deferrer.proto.
+package deferrerpb
+
+//go:generate protoc --go_out=plugins=grpc,paths=source_relative:. deferrer.proto
+
+const (
+	// ServiceName is the fully qualified name of the Deferrer service.
+	ServiceName = "giantswarm.shutdowndeferrer.v1.Deferrer"
+)
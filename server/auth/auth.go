@@ -0,0 +1,103 @@
+// Package auth implements the optional authentication of defer queries. The
+// credentials of a request are put into the request context by RequestFunc and
+// checked by the endpoint middleware returned by NewMiddleware.
+package auth
+
+import (
+	"context"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/giantswarm/microerror"
+	"github.com/giantswarm/micrologger"
+	kitendpoint "github.com/go-kit/kit/endpoint"
+)
+
+type credentialsKey struct{}
+
+type userKey struct{}
+
+// Credentials are the credentials presented by the client of a request.
+type Credentials struct {
+	// BearerToken is the token given in the Authorization header, if any.
+	BearerToken string
+	// PeerCertificates is the verified client certificate chain of a TLS
+	// connection, if any.
+	PeerCertificates []*x509.Certificate
+}
+
+// Authenticator authenticates the credentials of a request.
+type Authenticator interface {
+	// Authenticate returns the name of the authenticated client. It returns
+	// an unauthenticatedError when the credentials are missing or invalid.
+	Authenticate(ctx context.Context, credentials Credentials) (string, error)
+	// Name identifies the authentication method in logs.
+	Name() string
+}
+
+// RequestFunc puts the credentials of the given request into the context. It
+// is meant to be used as microkit server request function.
+func RequestFunc(ctx context.Context, r *http.Request) context.Context {
+	var credentials Credentials
+
+	const prefix = "Bearer "
+	header := r.Header.Get("Authorization")
+	if len(header) > len(prefix) && strings.EqualFold(header[:len(prefix)], prefix) {
+		credentials.BearerToken = strings.TrimSpace(header[len(prefix):])
+	}
+
+	if r.TLS != nil {
+		credentials.PeerCertificates = r.TLS.PeerCertificates
+	}
+
+	return NewContext(ctx, credentials)
+}
+
+// NewContext returns a copy of the given context carrying the given
+// credentials, e.g. for requests not received over HTTP.
+func NewContext(ctx context.Context, credentials Credentials) context.Context {
+	return context.WithValue(ctx, credentialsKey{}, credentials)
+}
+
+// FromContext returns the credentials put into the given context by
+// RequestFunc.
+func FromContext(ctx context.Context) (Credentials, bool) {
+	credentials, ok := ctx.Value(credentialsKey{}).(Credentials)
+	return credentials, ok
+}
+
+// UserFromContext returns the name of the client authenticated by the
+// middleware returned by NewMiddleware, if any.
+func UserFromContext(ctx context.Context) (string, bool) {
+	user, ok := ctx.Value(userKey{}).(string)
+	return user, ok
+}
+
+// NewMiddleware returns an endpoint middleware rejecting requests which are
+// not authenticated by any of the given authenticators. The name of the
+// authenticated client is put into the context, see UserFromContext.
+func NewMiddleware(logger micrologger.Logger, authenticators []Authenticator) kitendpoint.Middleware {
+	return func(next kitendpoint.Endpoint) kitendpoint.Endpoint {
+		return func(ctx context.Context, request interface{}) (interface{}, error) {
+			credentials, _ := FromContext(ctx)
+
+			for _, a := range authenticators {
+				user, err := a.Authenticate(ctx, credentials)
+				if IsUnauthenticated(err) {
+					continue
+				} else if err != nil {
+					_ = logger.LogCtx(ctx, "level", "warning", "message", fmt.Sprintf("failed to authenticate request using %s", a.Name()), "stack", fmt.Sprintf("%#v", err))
+					continue
+				}
+
+				_ = logger.LogCtx(ctx, "level", "debug", "message", fmt.Sprintf("authenticated %s using %s", user, a.Name()))
+
+				return next(context.WithValue(ctx, userKey{}, user), request)
+			}
+
+			return nil, microerror.Mask(unauthenticatedError)
+		}
+	}
+}
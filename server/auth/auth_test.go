@@ -0,0 +1,127 @@
+package auth
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/giantswarm/micrologger/microloggertest"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func Test_NewMiddleware(t *testing.T) {
+	dir, err := ioutil.TempDir("", "auth")
+	if err != nil {
+		t.Fatalf("error == %#v, want nil", err)
+	}
+	defer os.RemoveAll(dir)
+
+	tokenFile := filepath.Join(dir, "token")
+	err = ioutil.WriteFile(tokenFile, []byte("secret\n"), 0600)
+	if err != nil {
+		t.Fatalf("error == %#v, want nil", err)
+	}
+
+	var reviews int
+	k8sClient := fake.NewSimpleClientset()
+	k8sClient.PrependReactor("create", "tokenreviews", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		reviews++
+
+		review := action.(k8stesting.CreateAction).GetObject().(*authenticationv1.TokenReview)
+		if review.Spec.Token == "service-account" {
+			review.Status.Authenticated = true
+			review.Status.User.Username = "system:serviceaccount:bar:foo"
+		}
+
+		return true, review, nil
+	})
+
+	tokenAuthenticator, err := NewTokenAuthenticator(TokenAuthenticatorConfig{File: tokenFile})
+	if err != nil {
+		t.Fatalf("error == %#v, want nil", err)
+	}
+	tokenReviewAuthenticator, err := NewTokenReviewAuthenticator(TokenReviewAuthenticatorConfig{K8sClient: k8sClient})
+	if err != nil {
+		t.Fatalf("error == %#v, want nil", err)
+	}
+
+	middleware := NewMiddleware(microloggertest.New(), []Authenticator{tokenAuthenticator, tokenReviewAuthenticator})
+	endpoint := middleware(func(ctx context.Context, request interface{}) (interface{}, error) {
+		user, _ := UserFromContext(ctx)
+		return user, nil
+	})
+
+	testCases := []struct {
+		name          string
+		authorization string
+		expectedUser  string
+		errorMatcher  func(error) bool
+	}{
+		{
+			name:          "case 0: should reject request without token",
+			authorization: "",
+			errorMatcher:  IsUnauthenticated,
+		},
+		{
+			name:          "case 1: should accept request with shared token",
+			authorization: "Bearer secret",
+			expectedUser:  "token holder",
+			errorMatcher:  nil,
+		},
+		{
+			name:          "case 2: should accept request with reviewed service account token",
+			authorization: "bearer service-account",
+			expectedUser:  "system:serviceaccount:bar:foo",
+			errorMatcher:  nil,
+		},
+		{
+			name:          "case 3: should reject request with unknown token",
+			authorization: "Bearer unknown",
+			errorMatcher:  IsUnauthenticated,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/v1/defer/", nil)
+			if tc.authorization != "" {
+				r.Header.Set("Authorization", tc.authorization)
+			}
+
+			user, err := endpoint(RequestFunc(context.Background(), r), nil)
+
+			switch {
+			case err == nil && tc.errorMatcher == nil:
+				// correct; carry on
+			case err != nil && tc.errorMatcher == nil:
+				t.Fatalf("error == %#v, want nil", err)
+			case err == nil && tc.errorMatcher != nil:
+				t.Fatalf("error == nil, want non-nil")
+			case !tc.errorMatcher(err):
+				t.Fatalf("error == %#v, want matching", err)
+			}
+
+			if tc.errorMatcher == nil && user != tc.expectedUser {
+				t.Fatalf("user == %q, want %q", user, tc.expectedUser)
+			}
+		})
+	}
+
+	// Successful reviews are cached.
+	reviewsBefore := reviews
+	r := httptest.NewRequest("GET", "/v1/defer/", nil)
+	r.Header.Set("Authorization", "Bearer service-account")
+	_, err = endpoint(RequestFunc(context.Background(), r), nil)
+	if err != nil {
+		t.Fatalf("error == %#v, want nil", err)
+	}
+	if reviews != reviewsBefore {
+		t.Fatalf("reviews == %d, want %d", reviews, reviewsBefore)
+	}
+}
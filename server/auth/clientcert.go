@@ -0,0 +1,89 @@
+package auth
+
+import (
+	"context"
+	"crypto/x509"
+	"io/ioutil"
+
+	"github.com/giantswarm/microerror"
+)
+
+const (
+	// MethodClientCert is the name of the mTLS client certificate
+	// authentication.
+	MethodClientCert = "clientcert"
+)
+
+type ClientCertAuthenticatorConfig struct {
+	// CAFile contains the certificate authorities client certificates must be
+	// signed by.
+	CAFile string
+}
+
+// ClientCertAuthenticator authenticates clients presenting a certificate
+// signed by the configured certificate authorities. It requires the server to
+// terminate TLS and request client certificates.
+type ClientCertAuthenticator struct {
+	pool *x509.CertPool
+}
+
+func NewClientCertAuthenticator(config ClientCertAuthenticatorConfig) (*ClientCertAuthenticator, error) {
+	if config.CAFile == "" {
+		return nil, microerror.Maskf(invalidConfigError, "%T.CAFile must not be empty", config)
+	}
+
+	pool, err := LoadCertPool(config.CAFile)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	a := &ClientCertAuthenticator{
+		pool: pool,
+	}
+
+	return a, nil
+}
+
+func (a *ClientCertAuthenticator) Authenticate(ctx context.Context, credentials Credentials) (string, error) {
+	if len(credentials.PeerCertificates) == 0 {
+		return "", microerror.Mask(unauthenticatedError)
+	}
+
+	intermediates := x509.NewCertPool()
+	for _, c := range credentials.PeerCertificates[1:] {
+		intermediates.AddCert(c)
+	}
+
+	opts := x509.VerifyOptions{
+		Intermediates: intermediates,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		Roots:         a.pool,
+	}
+
+	_, err := credentials.PeerCertificates[0].Verify(opts)
+	if err != nil {
+		return "", microerror.Maskf(unauthenticatedError, "%s", err)
+	}
+
+	return credentials.PeerCertificates[0].Subject.CommonName, nil
+}
+
+func (a *ClientCertAuthenticator) Name() string {
+	return MethodClientCert
+}
+
+// LoadCertPool returns a pool of the PEM encoded certificates in the given
+// file.
+func LoadCertPool(file string) (*x509.CertPool, error) {
+	b, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(b) {
+		return nil, microerror.Maskf(invalidConfigError, "CA file %#q must contain PEM encoded certificates", file)
+	}
+
+	return pool, nil
+}
@@ -0,0 +1,80 @@
+package auth
+
+import (
+	"context"
+	"crypto/subtle"
+	"io/ioutil"
+	"strings"
+
+	"github.com/giantswarm/microerror"
+)
+
+const (
+	// MethodToken is the name of the shared bearer token authentication.
+	MethodToken = "token"
+)
+
+type TokenAuthenticatorConfig struct {
+	// File contains the shared bearer token, e.g. mounted from a Secret. It is
+	// read on every request, so that the token can be rotated without
+	// restarting.
+	File string
+}
+
+// TokenAuthenticator authenticates requests presenting a shared bearer token.
+type TokenAuthenticator struct {
+	file string
+}
+
+func NewTokenAuthenticator(config TokenAuthenticatorConfig) (*TokenAuthenticator, error) {
+	if config.File == "" {
+		return nil, microerror.Maskf(invalidConfigError, "%T.File must not be empty", config)
+	}
+
+	// The token is read once to fail at startup when the file is missing.
+	_, err := readToken(config.File)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	a := &TokenAuthenticator{
+		file: config.File,
+	}
+
+	return a, nil
+}
+
+func (a *TokenAuthenticator) Authenticate(ctx context.Context, credentials Credentials) (string, error) {
+	if credentials.BearerToken == "" {
+		return "", microerror.Mask(unauthenticatedError)
+	}
+
+	token, err := readToken(a.file)
+	if err != nil {
+		return "", microerror.Mask(err)
+	}
+
+	if subtle.ConstantTimeCompare([]byte(credentials.BearerToken), []byte(token)) != 1 {
+		return "", microerror.Mask(unauthenticatedError)
+	}
+
+	return "token holder", nil
+}
+
+func (a *TokenAuthenticator) Name() string {
+	return MethodToken
+}
+
+func readToken(file string) (string, error) {
+	b, err := ioutil.ReadFile(file)
+	if err != nil {
+		return "", microerror.Mask(err)
+	}
+
+	token := strings.TrimSpace(string(b))
+	if token == "" {
+		return "", microerror.Maskf(invalidConfigError, "token file %#q must not be empty", file)
+	}
+
+	return token, nil
+}
@@ -0,0 +1,113 @@
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"sync"
+	"time"
+
+	"github.com/giantswarm/microerror"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	// MethodTokenReview is the name of the Kubernetes TokenReview
+	// authentication.
+	MethodTokenReview = "tokenreview"
+)
+
+const (
+	// tokenReviewCacheDuration is the time a successful TokenReview is reused,
+	// so that polling clients do not flood the API server.
+	tokenReviewCacheDuration = 1 * time.Minute
+)
+
+type TokenReviewAuthenticatorConfig struct {
+	K8sClient kubernetes.Interface
+
+	// Audiences are the audiences the token must be valid for. The audience of
+	// the API server is used when empty.
+	Audiences []string
+}
+
+// TokenReviewAuthenticator authenticates in-cluster clients presenting a
+// service account token, which is reviewed using the Kubernetes TokenReview
+// API.
+type TokenReviewAuthenticator struct {
+	k8sClient kubernetes.Interface
+
+	audiences []string
+
+	cache map[[sha256.Size]byte]tokenReviewCacheEntry
+	mutex sync.Mutex
+}
+
+type tokenReviewCacheEntry struct {
+	expires time.Time
+	user    string
+}
+
+func NewTokenReviewAuthenticator(config TokenReviewAuthenticatorConfig) (*TokenReviewAuthenticator, error) {
+	if config.K8sClient == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.K8sClient must not be empty", config)
+	}
+
+	a := &TokenReviewAuthenticator{
+		k8sClient: config.K8sClient,
+
+		audiences: config.Audiences,
+
+		cache: map[[sha256.Size]byte]tokenReviewCacheEntry{},
+	}
+
+	return a, nil
+}
+
+func (a *TokenReviewAuthenticator) Authenticate(ctx context.Context, credentials Credentials) (string, error) {
+	if credentials.BearerToken == "" {
+		return "", microerror.Mask(unauthenticatedError)
+	}
+
+	key := sha256.Sum256([]byte(credentials.BearerToken))
+
+	a.mutex.Lock()
+	entry, ok := a.cache[key]
+	a.mutex.Unlock()
+	if ok && time.Now().Before(entry.expires) {
+		return entry.user, nil
+	}
+
+	review := &authenticationv1.TokenReview{
+		Spec: authenticationv1.TokenReviewSpec{
+			Audiences: a.audiences,
+			Token:     credentials.BearerToken,
+		},
+	}
+
+	review, err := a.k8sClient.AuthenticationV1().TokenReviews().Create(review)
+	if err != nil {
+		return "", microerror.Mask(err)
+	}
+	if !review.Status.Authenticated {
+		return "", microerror.Maskf(unauthenticatedError, "%s", review.Status.Error)
+	}
+
+	a.mutex.Lock()
+	for k, e := range a.cache {
+		if time.Now().After(e.expires) {
+			delete(a.cache, k)
+		}
+	}
+	a.cache[key] = tokenReviewCacheEntry{
+		expires: time.Now().Add(tokenReviewCacheDuration),
+		user:    review.Status.User.Username,
+	}
+	a.mutex.Unlock()
+
+	return review.Status.User.Username, nil
+}
+
+func (a *TokenReviewAuthenticator) Name() string {
+	return MethodTokenReview
+}
@@ -2,14 +2,19 @@ package deferrer
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"mime"
 	"net/http"
+	"strings"
+	"time"
 
 	"github.com/giantswarm/microerror"
 	"github.com/giantswarm/micrologger"
 	kitendpoint "github.com/go-kit/kit/endpoint"
 	kithttp "github.com/go-kit/kit/transport/http"
 
+	"github.com/giantswarm/shutdown-deferrer/service/audit"
 	"github.com/giantswarm/shutdown-deferrer/service/deferrer"
 )
 
@@ -25,18 +30,43 @@ const (
 // Config represents the configuration used to create a lister endpoint.
 type Config struct {
 	// Dependencies.
+	Audit    *audit.Logger
 	Deferrer *deferrer.Service
 	Logger   micrologger.Logger
+
+	// Middlewares are applied to the endpoint, e.g. to authenticate requests.
+	Middlewares []kitendpoint.Middleware
 }
 
 type Endpoint struct {
+	audit    *audit.Logger
 	deferrer *deferrer.Service
 	logger   micrologger.Logger
+
+	middlewares []kitendpoint.Middleware
+}
+
+// Request is the decoded request of the deferrer endpoint.
+type Request struct {
+	// Caller identifies the client for the audit trail.
+	Caller audit.Caller
+	// Start is the time the request was received.
+	Start time.Time
+	// JSON is true when the client accepts application/json responses. The
+	// plain text response is used otherwise.
+	JSON bool
+	// DrainerConfigTarget overrides the DrainerConfig looked up for this
+	// request. It is given with the query parameters drainerconfig.name,
+	// drainerconfig.namespace and drainerconfig.labelselector.
+	DrainerConfigTarget *deferrer.DrainerConfigTarget
 }
 
 // New creates a new configured lister object.
 func New(config Config) (*Endpoint, error) {
 	// Dependencies.
+	if config.Audit == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Audit must not be empty", config)
+	}
 	if config.Deferrer == nil {
 		return nil, microerror.Maskf(invalidConfigError, "%T.Deferrer must not be empty", config)
 	}
@@ -45,8 +75,11 @@ func New(config Config) (*Endpoint, error) {
 	}
 
 	e := &Endpoint{
+		audit:    config.Audit,
 		deferrer: config.Deferrer,
 		logger:   config.Logger,
+
+		middlewares: config.Middlewares,
 	}
 
 	return e, nil
@@ -54,30 +87,71 @@ func New(config Config) (*Endpoint, error) {
 
 func (e *Endpoint) Decoder() kithttp.DecodeRequestFunc {
 	return func(ctx context.Context, r *http.Request) (interface{}, error) {
-		return nil, nil
+		request := Request{
+			Caller: audit.NewCaller(r),
+			Start:  time.Now(),
+			JSON:   AcceptsJSON(r.Header.Get("Accept")),
+		}
+
+		query := r.URL.Query()
+		target := deferrer.DrainerConfigTarget{
+			Name:          query.Get("drainerconfig.name"),
+			Namespace:     query.Get("drainerconfig.namespace"),
+			LabelSelector: query.Get("drainerconfig.labelselector"),
+		}
+		if target != (deferrer.DrainerConfigTarget{}) {
+			err := target.Validate()
+			if err != nil {
+				return nil, microerror.Maskf(invalidRequestError, "invalid drainerconfig target: %s", err)
+			}
+
+			request.DrainerConfigTarget = &target
+		}
+
+		return request, nil
 	}
 }
 
 func (e *Endpoint) Encoder() kithttp.EncodeResponseFunc {
 	return func(ctx context.Context, w http.ResponseWriter, response interface{}) error {
-		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
-		w.WriteHeader(http.StatusOK)
-		b, ok := response.([]byte)
-		if !ok {
+		switch r := response.(type) {
+		case []byte:
+			w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+			w.WriteHeader(http.StatusOK)
+			_, err := w.Write(r)
+			return err
+		case Response:
+			w.Header().Set("Content-Type", "application/json; charset=utf-8")
+			w.WriteHeader(http.StatusOK)
+			return json.NewEncoder(w).Encode(r)
+		default:
 			return microerror.Mask(invalidResponseTypeError)
 		}
-		_, err := w.Write(b)
-		return err
 	}
 }
 
 func (e *Endpoint) Endpoint() kitendpoint.Endpoint {
 	return func(ctx context.Context, request interface{}) (interface{}, error) {
-		response, err := e.deferrer.ShouldDefer(ctx)
+		r, ok := request.(Request)
+		if !ok {
+			return nil, microerror.Maskf(invalidRequestTypeError, "expected %T, got %T", r, request)
+		}
+
+		if r.DrainerConfigTarget != nil {
+			ctx = deferrer.NewDrainerConfigTargetContext(ctx, *r.DrainerConfigTarget)
+		}
+
+		decision, err := e.deferrer.Decide(ctx)
+		e.writeAudit(ctx, audit.NewEntry(Name, r.Caller, r.Start, decision, err))
 		if err != nil {
 			return nil, microerror.Mask(err)
 		}
-		return []byte(fmt.Sprintf("%t", response)), nil
+
+		if r.JSON {
+			return NewResponse(decision), nil
+		}
+
+		return []byte(fmt.Sprintf("%t", decision.Defer)), nil
 	}
 }
 
@@ -87,7 +161,7 @@ func (e *Endpoint) Method() string {
 
 // Middlewares returns a slice of the middlewares used in this endpoint.
 func (e *Endpoint) Middlewares() []kitendpoint.Middleware {
-	return []kitendpoint.Middleware{}
+	return e.middlewares
 }
 
 func (e *Endpoint) Name() string {
@@ -97,3 +171,28 @@ func (e *Endpoint) Name() string {
 func (e *Endpoint) Path() string {
 	return Path
 }
+
+// writeAudit writes the given entry to the audit trail. Failures are logged
+// but do not fail the query.
+func (e *Endpoint) writeAudit(ctx context.Context, entry audit.Entry) {
+	err := e.audit.Log(entry)
+	if err != nil {
+		_ = e.logger.LogCtx(ctx, "level", "warning", "message", "failed to write audit trail", "stack", fmt.Sprintf("%#v", err))
+	}
+}
+
+// AcceptsJSON returns true when the given Accept header value contains the
+// application/json media type.
+func AcceptsJSON(accept string) bool {
+	for _, v := range strings.Split(accept, ",") {
+		mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(v))
+		if err != nil {
+			continue
+		}
+		if mediaType == "application/json" {
+			return true
+		}
+	}
+
+	return false
+}
@@ -11,6 +11,15 @@ func IsInvalidConfig(err error) bool {
 	return microerror.Cause(err) == invalidConfigError
 }
 
+var invalidRequestError = &microerror.Error{
+	Kind: "invalidRequestError",
+}
+
+// IsInvalidRequest asserts invalidRequestError.
+func IsInvalidRequest(err error) bool {
+	return microerror.Cause(err) == invalidRequestError
+}
+
 var invalidResponseTypeError = &microerror.Error{
 	Kind: "invalidResponseTypeError",
 }
@@ -19,3 +28,12 @@ var invalidResponseTypeError = &microerror.Error{
 func IsInvalidResponseType(err error) bool {
 	return microerror.Cause(err) == invalidResponseTypeError
 }
+
+var invalidRequestTypeError = &microerror.Error{
+	Kind: "invalidRequestTypeError",
+}
+
+// IsInvalidRequestType asserts invalidRequestTypeError.
+func IsInvalidRequestType(err error) bool {
+	return microerror.Cause(err) == invalidRequestTypeError
+}
@@ -0,0 +1,207 @@
+package override
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/giantswarm/microerror"
+	"github.com/giantswarm/micrologger"
+	kitendpoint "github.com/go-kit/kit/endpoint"
+	kithttp "github.com/go-kit/kit/transport/http"
+
+	"github.com/giantswarm/shutdown-deferrer/server/auth"
+	deferrerendpoint "github.com/giantswarm/shutdown-deferrer/server/endpoint/deferrer"
+	"github.com/giantswarm/shutdown-deferrer/service/deferrer"
+)
+
+const (
+	// MethodDelete is the HTTP method of the endpoint removing the override.
+	MethodDelete = "DELETE"
+	// MethodPut is the HTTP method of the endpoint setting the override.
+	MethodPut = "PUT"
+	// Name identifies the endpoint. It is aligned to the package path.
+	Name = "deferrer/override"
+	// Path is the HTTP request path this endpoint is registered for.
+	Path = "/v1/defer/override"
+)
+
+const (
+	// DefaultTTL is the time an override is honoured when no ttl is given.
+	DefaultTTL = time.Hour
+	// MaxTTL is the upper bound for the ttl of an override, so that forgotten
+	// overrides do not stick around forever.
+	MaxTTL = 7 * 24 * time.Hour
+)
+
+// Config represents the configuration used to create an override endpoint.
+type Config struct {
+	// Dependencies.
+	Deferrer *deferrer.Service
+	Logger   micrologger.Logger
+
+	// Method is one of MethodDelete or MethodPut.
+	Method string
+	// Middlewares are applied to the endpoint. They must authenticate
+	// requests, because anyone able to set an override controls node
+	// termination.
+	Middlewares []kitendpoint.Middleware
+}
+
+type Endpoint struct {
+	deferrer *deferrer.Service
+	logger   micrologger.Logger
+
+	method      string
+	middlewares []kitendpoint.Middleware
+}
+
+// Request is the decoded request of the override endpoint.
+type Request struct {
+	// Override is the override to set. It is nil when the override is
+	// removed.
+	Override *deferrer.Override
+}
+
+// RequestBody is the JSON body of requests setting the override.
+type RequestBody struct {
+	// Force is one of deferrer.ForceAllow or deferrer.ForceDefer.
+	Force string `json:"force"`
+	// Reason explains why the override is set.
+	Reason string `json:"reason"`
+	// TTL is the duration like 30m the override is honoured. Defaults to
+	// DefaultTTL.
+	TTL string `json:"ttl"`
+}
+
+// New creates a new configured override endpoint.
+func New(config Config) (*Endpoint, error) {
+	// Dependencies.
+	if config.Deferrer == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Deferrer must not be empty", config)
+	}
+	if config.Logger == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Logger must not be empty", config)
+	}
+
+	if config.Method != MethodDelete && config.Method != MethodPut {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Method must be one of %q or %q", config, MethodDelete, MethodPut)
+	}
+	if len(config.Middlewares) == 0 {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Middlewares must not be empty", config)
+	}
+
+	e := &Endpoint{
+		deferrer: config.Deferrer,
+		logger:   config.Logger,
+
+		method:      config.Method,
+		middlewares: config.Middlewares,
+	}
+
+	return e, nil
+}
+
+func (e *Endpoint) Decoder() kithttp.DecodeRequestFunc {
+	return func(ctx context.Context, r *http.Request) (interface{}, error) {
+		if e.method == MethodDelete {
+			return Request{}, nil
+		}
+
+		var body RequestBody
+		err := json.NewDecoder(r.Body).Decode(&body)
+		if err != nil {
+			return nil, microerror.Maskf(invalidRequestError, "body must be a JSON object with the fields force, reason and ttl: %s", err)
+		}
+
+		ttl := DefaultTTL
+		if body.TTL != "" {
+			ttl, err = time.ParseDuration(body.TTL)
+			if err != nil {
+				return nil, microerror.Maskf(invalidRequestError, "ttl must be a duration like 30m: %s", err)
+			}
+			if ttl <= 0 || ttl > MaxTTL {
+				return nil, microerror.Maskf(invalidRequestError, "ttl must be greater than 0s and at most %s", MaxTTL)
+			}
+		}
+
+		override := deferrer.Override{
+			Force:   body.Force,
+			Reason:  body.Reason,
+			Expires: time.Now().Add(ttl).UTC().Truncate(time.Second),
+		}
+
+		err = override.Validate()
+		if err != nil {
+			return nil, microerror.Maskf(invalidRequestError, "%s", err)
+		}
+
+		return Request{Override: &override}, nil
+	}
+}
+
+func (e *Endpoint) Encoder() kithttp.EncodeResponseFunc {
+	return func(ctx context.Context, w http.ResponseWriter, response interface{}) error {
+		switch r := response.(type) {
+		case nil:
+			w.WriteHeader(http.StatusNoContent)
+			return nil
+		case *deferrerendpoint.ResponseOverride:
+			w.Header().Set("Content-Type", "application/json; charset=utf-8")
+			w.WriteHeader(http.StatusOK)
+			return json.NewEncoder(w).Encode(r)
+		default:
+			return microerror.Mask(invalidResponseTypeError)
+		}
+	}
+}
+
+func (e *Endpoint) Endpoint() kitendpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		r, ok := request.(Request)
+		if !ok {
+			return nil, microerror.Maskf(invalidRequestError, "expected %T, got %T", r, request)
+		}
+
+		if r.Override == nil {
+			err := e.deferrer.DeleteOverride(ctx)
+			if deferrer.IsInvalidConfig(err) {
+				return nil, microerror.Maskf(invalidRequestError, "%s", err)
+			} else if err != nil {
+				return nil, microerror.Mask(err)
+			}
+
+			return nil, nil
+		}
+
+		override := *r.Override
+		override.User, _ = auth.UserFromContext(ctx)
+
+		err := e.deferrer.SetOverride(ctx, override)
+		if deferrer.IsInvalidConfig(err) {
+			return nil, microerror.Maskf(invalidRequestError, "%s", err)
+		} else if err != nil {
+			return nil, microerror.Mask(err)
+		}
+
+		return deferrerendpoint.NewResponseOverride(override), nil
+	}
+}
+
+func (e *Endpoint) Method() string {
+	return e.method
+}
+
+// Middlewares returns a slice of the middlewares used in this endpoint.
+func (e *Endpoint) Middlewares() []kitendpoint.Middleware {
+	return e.middlewares
+}
+
+func (e *Endpoint) Name() string {
+	return Name
+}
+
+func (e *Endpoint) Path() string {
+	return Path
+}
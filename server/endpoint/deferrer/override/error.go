@@ -0,0 +1,30 @@
+package override
+
+import "github.com/giantswarm/microerror"
+
+var invalidConfigError = &microerror.Error{
+	Kind: "invalidConfigError",
+}
+
+// IsInvalidConfig asserts invalidConfigError.
+func IsInvalidConfig(err error) bool {
+	return microerror.Cause(err) == invalidConfigError
+}
+
+var invalidRequestError = &microerror.Error{
+	Kind: "invalidRequestError",
+}
+
+// IsInvalidRequest asserts invalidRequestError.
+func IsInvalidRequest(err error) bool {
+	return microerror.Cause(err) == invalidRequestError
+}
+
+var invalidResponseTypeError = &microerror.Error{
+	Kind: "invalidResponseTypeError",
+}
+
+// IsInvalidResponseType asserts invalidResponseTypeError.
+func IsInvalidResponseType(err error) bool {
+	return microerror.Cause(err) == invalidResponseTypeError
+}
@@ -0,0 +1,172 @@
+package pod
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/giantswarm/microerror"
+	"github.com/giantswarm/micrologger"
+	kitendpoint "github.com/go-kit/kit/endpoint"
+	kithttp "github.com/go-kit/kit/transport/http"
+	"github.com/gorilla/mux"
+
+	deferrerendpoint "github.com/giantswarm/shutdown-deferrer/server/endpoint/deferrer"
+	"github.com/giantswarm/shutdown-deferrer/service/audit"
+	"github.com/giantswarm/shutdown-deferrer/service/deferrer"
+)
+
+const (
+	// Method is the HTTP method this endpoint is registered for.
+	Method = "GET"
+	// Name identifies the endpoint. It is aligned to the package path.
+	Name = "deferrer/pod"
+	// Path is the HTTP request path this endpoint is registered for. It is
+	// served in multi-tenant mode to decide for arbitrary PODs.
+	Path = "/v1/defer/{namespace}/{pod}"
+)
+
+// Config represents the configuration used to create a pod endpoint.
+type Config struct {
+	// Dependencies.
+	Audit    *audit.Logger
+	Deferrer *deferrer.Service
+	Logger   micrologger.Logger
+
+	// Middlewares are applied to the endpoint, e.g. to authenticate requests.
+	Middlewares []kitendpoint.Middleware
+}
+
+type Endpoint struct {
+	audit    *audit.Logger
+	deferrer *deferrer.Service
+	logger   micrologger.Logger
+
+	middlewares []kitendpoint.Middleware
+}
+
+// Request is the decoded request of the pod endpoint.
+type Request struct {
+	// Caller identifies the client for the audit trail.
+	Caller audit.Caller
+	// Start is the time the request was received.
+	Start time.Time
+	// JSON is true when the client accepts application/json responses. The
+	// plain text response is used otherwise.
+	JSON bool
+	// Pod is the POD the decision is requested for.
+	Pod deferrer.Pod
+}
+
+// New creates a new configured pod endpoint.
+func New(config Config) (*Endpoint, error) {
+	// Dependencies.
+	if config.Audit == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Audit must not be empty", config)
+	}
+	if config.Deferrer == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Deferrer must not be empty", config)
+	}
+	if config.Logger == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Logger must not be empty", config)
+	}
+
+	e := &Endpoint{
+		audit:    config.Audit,
+		deferrer: config.Deferrer,
+		logger:   config.Logger,
+
+		middlewares: config.Middlewares,
+	}
+
+	return e, nil
+}
+
+func (e *Endpoint) Decoder() kithttp.DecodeRequestFunc {
+	return func(ctx context.Context, r *http.Request) (interface{}, error) {
+		vars := mux.Vars(r)
+
+		request := Request{
+			Caller: audit.NewCaller(r),
+			Start:  time.Now(),
+			JSON:   deferrerendpoint.AcceptsJSON(r.Header.Get("Accept")),
+			Pod: deferrer.Pod{
+				Name:      vars["pod"],
+				Namespace: vars["namespace"],
+			},
+		}
+
+		return request, nil
+	}
+}
+
+func (e *Endpoint) Encoder() kithttp.EncodeResponseFunc {
+	return func(ctx context.Context, w http.ResponseWriter, response interface{}) error {
+		switch r := response.(type) {
+		case []byte:
+			w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+			w.WriteHeader(http.StatusOK)
+			_, err := w.Write(r)
+			return err
+		case deferrerendpoint.Response:
+			w.Header().Set("Content-Type", "application/json; charset=utf-8")
+			w.WriteHeader(http.StatusOK)
+			return json.NewEncoder(w).Encode(r)
+		default:
+			return microerror.Mask(invalidResponseTypeError)
+		}
+	}
+}
+
+func (e *Endpoint) Endpoint() kitendpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		r, ok := request.(Request)
+		if !ok {
+			return nil, microerror.Maskf(invalidRequestTypeError, "expected %T, got %T", r, request)
+		}
+
+		decision, err := e.deferrer.DecideFor(ctx, r.Pod)
+
+		entry := audit.NewEntry(Name, r.Caller, r.Start, decision, err)
+		entry.Pod = fmt.Sprintf("%s/%s", r.Pod.Namespace, r.Pod.Name)
+		e.writeAudit(ctx, entry)
+
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+
+		if r.JSON {
+			return deferrerendpoint.NewResponse(decision), nil
+		}
+
+		return []byte(fmt.Sprintf("%t", decision.Defer)), nil
+	}
+}
+
+func (e *Endpoint) Method() string {
+	return Method
+}
+
+// Middlewares returns a slice of the middlewares used in this endpoint.
+func (e *Endpoint) Middlewares() []kitendpoint.Middleware {
+	return e.middlewares
+}
+
+func (e *Endpoint) Name() string {
+	return Name
+}
+
+func (e *Endpoint) Path() string {
+	return Path
+}
+
+// writeAudit writes the given entry to the audit trail. Failures are logged
+// but do not fail the query.
+func (e *Endpoint) writeAudit(ctx context.Context, entry audit.Entry) {
+	err := e.audit.Log(entry)
+	if err != nil {
+		_ = e.logger.LogCtx(ctx, "level", "warning", "message", "failed to write audit trail", "stack", fmt.Sprintf("%#v", err))
+	}
+}
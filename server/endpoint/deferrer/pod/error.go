@@ -0,0 +1,30 @@
+package pod
+
+import "github.com/giantswarm/microerror"
+
+var invalidConfigError = &microerror.Error{
+	Kind: "invalidConfigError",
+}
+
+// IsInvalidConfig asserts invalidConfigError.
+func IsInvalidConfig(err error) bool {
+	return microerror.Cause(err) == invalidConfigError
+}
+
+var invalidResponseTypeError = &microerror.Error{
+	Kind: "invalidResponseTypeError",
+}
+
+// IsInvalidResponseType asserts invalidResponseTypeError.
+func IsInvalidResponseType(err error) bool {
+	return microerror.Cause(err) == invalidResponseTypeError
+}
+
+var invalidRequestTypeError = &microerror.Error{
+	Kind: "invalidRequestTypeError",
+}
+
+// IsInvalidRequestType asserts invalidRequestTypeError.
+func IsInvalidRequestType(err error) bool {
+	return microerror.Cause(err) == invalidRequestTypeError
+}
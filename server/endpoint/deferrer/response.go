@@ -0,0 +1,86 @@
+package deferrer
+
+import (
+	"time"
+
+	"github.com/giantswarm/shutdown-deferrer/service/deferrer"
+)
+
+// Response is the JSON representation of a defer decision. It is returned when
+// the client accepts application/json.
+type Response struct {
+	Defer    bool   `json:"defer"`
+	Reason   string `json:"reason"`
+	Provider string `json:"provider"`
+
+	DrainerConfig *ResponseDrainerConfig `json:"drainerConfig,omitempty"`
+	Condition     *ResponseCondition     `json:"condition,omitempty"`
+	Override      *ResponseOverride      `json:"override,omitempty"`
+
+	// Error is the message of the error that occurred while looking up the
+	// DrainerConfig, if any. The decision is made according to the configured
+	// failure policy in this case.
+	Error string `json:"error,omitempty"`
+}
+
+type ResponseDrainerConfig struct {
+	Name            string `json:"name"`
+	Namespace       string `json:"namespace"`
+	ResourceVersion string `json:"resourceVersion"`
+}
+
+type ResponseCondition struct {
+	LastTransitionTime time.Time `json:"lastTransitionTime"`
+	Status             string    `json:"status"`
+	Type               string    `json:"type"`
+}
+
+type ResponseOverride struct {
+	Force   string    `json:"force"`
+	Reason  string    `json:"reason"`
+	Expires time.Time `json:"expires"`
+	User    string    `json:"user,omitempty"`
+}
+
+// NewResponseOverride creates the JSON representation of the given override.
+func NewResponseOverride(override deferrer.Override) *ResponseOverride {
+	return &ResponseOverride{
+		Force:   override.Force,
+		Reason:  override.Reason,
+		Expires: override.Expires,
+		User:    override.User,
+	}
+}
+
+// NewResponse creates the JSON representation of the given decision.
+func NewResponse(decision deferrer.Decision) Response {
+	r := Response{
+		Defer:    decision.Defer,
+		Reason:   decision.Reason,
+		Provider: decision.Provider,
+
+		Error: decision.Error,
+	}
+
+	if decision.DrainerConfig != nil {
+		r.DrainerConfig = &ResponseDrainerConfig{
+			Name:            decision.DrainerConfig.Name,
+			Namespace:       decision.DrainerConfig.Namespace,
+			ResourceVersion: decision.DrainerConfig.ResourceVersion,
+		}
+	}
+
+	if decision.Condition != nil {
+		r.Condition = &ResponseCondition{
+			LastTransitionTime: decision.Condition.LastTransitionTime,
+			Status:             decision.Condition.Status,
+			Type:               decision.Condition.Type,
+		}
+	}
+
+	if decision.Override != nil {
+		r.Override = NewResponseOverride(*decision.Override)
+	}
+
+	return r
+}
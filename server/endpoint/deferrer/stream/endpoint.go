@@ -0,0 +1,212 @@
+package stream
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"time"
+
+	"github.com/giantswarm/microerror"
+	"github.com/giantswarm/micrologger"
+	kitendpoint "github.com/go-kit/kit/endpoint"
+
+	"github.com/giantswarm/shutdown-deferrer/server/auth"
+	deferrerendpoint "github.com/giantswarm/shutdown-deferrer/server/endpoint/deferrer"
+	"github.com/giantswarm/shutdown-deferrer/service/deferrer"
+)
+
+const (
+	// Method is the HTTP method this endpoint is registered for.
+	Method = "GET"
+	// Name identifies the endpoint. It is aligned to the package path.
+	Name = "deferrer/stream"
+	// Path is the HTTP request path this endpoint is registered for.
+	Path = "/v1/defer/stream"
+)
+
+const (
+	// EventDecision is the type of events carrying the JSON representation of
+	// a decision.
+	EventDecision = "decision"
+	// EventError is the type of the event sent before the stream is closed
+	// because deciding failed.
+	EventError = "error"
+)
+
+const (
+	// KeepAliveInterval is the interval in which comments are sent while the
+	// decision does not change, so that proxies do not close idle streams.
+	KeepAliveInterval = 30 * time.Second
+)
+
+// Config represents the configuration used to create a stream endpoint.
+type Config struct {
+	// Dependencies.
+	Deferrer *deferrer.Service
+	Logger   micrologger.Logger
+
+	// Middlewares are applied before streaming, e.g. to authenticate
+	// requests.
+	Middlewares []kitendpoint.Middleware
+}
+
+// Endpoint streams the decision for the POD the service is running in and
+// every change of it as Server-Sent Events. Other than the microkit endpoints
+// it is a plain http.Handler, because microkit buffers response bodies and
+// does not support flushing them.
+type Endpoint struct {
+	deferrer *deferrer.Service
+	logger   micrologger.Logger
+
+	middlewares []kitendpoint.Middleware
+}
+
+// New creates a new configured stream endpoint.
+func New(config Config) (*Endpoint, error) {
+	// Dependencies.
+	if config.Deferrer == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Deferrer must not be empty", config)
+	}
+	if config.Logger == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Logger must not be empty", config)
+	}
+
+	e := &Endpoint{
+		deferrer: config.Deferrer,
+		logger:   config.Logger,
+
+		middlewares: config.Middlewares,
+	}
+
+	return e, nil
+}
+
+func (e *Endpoint) Method() string {
+	return Method
+}
+
+func (e *Endpoint) Name() string {
+	return Name
+}
+
+func (e *Endpoint) Path() string {
+	return Path
+}
+
+// ServeHTTP sends the current decision as first event and then every decision
+// which differs from the previous one, until the client goes away or deciding
+// fails.
+func (e *Endpoint) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+
+	err := e.authenticate(ctx, r)
+	if auth.IsUnauthenticated(err) {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		http.Error(w, "authentication required", http.StatusUnauthorized)
+		return
+	} else if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming is not supported", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	decisions := make(chan deferrer.Decision)
+	watchErr := make(chan error, 1)
+	go func() {
+		watchErr <- e.deferrer.Watch(ctx, func(decision deferrer.Decision) bool {
+			select {
+			case decisions <- decision:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		})
+	}()
+
+	keepAlive := time.NewTicker(KeepAliveInterval)
+	defer keepAlive.Stop()
+
+	var id int
+	var last *deferrerendpoint.Response
+	for {
+		var err error
+
+		select {
+		case decision := <-decisions:
+			response := deferrerendpoint.NewResponse(decision)
+			if last != nil && reflect.DeepEqual(*last, response) {
+				continue
+			}
+			last = &response
+
+			id++
+			err = writeEvent(w, id, EventDecision, response)
+		case <-keepAlive.C:
+			_, err = io.WriteString(w, ": keep-alive\n\n")
+		case err = <-watchErr:
+			if err != nil {
+				_ = e.logger.LogCtx(ctx, "level", "warning", "message", "failed to watch decision", "stack", fmt.Sprintf("%#v", err))
+
+				id++
+				_ = writeEvent(w, id, EventError, deferrerendpoint.Response{Error: err.Error()})
+				flusher.Flush()
+			}
+			return
+		case <-ctx.Done():
+			return
+		}
+
+		if err != nil {
+			_ = e.logger.LogCtx(ctx, "level", "debug", "message", "failed to write event", "stack", fmt.Sprintf("%#v", err))
+			return
+		}
+
+		flusher.Flush()
+	}
+}
+
+// authenticate applies the middlewares to a no-op endpoint, so that requests
+// are authenticated like the ones of the microkit endpoints.
+func (e *Endpoint) authenticate(ctx context.Context, r *http.Request) error {
+	endpoint := func(ctx context.Context, request interface{}) (interface{}, error) {
+		return nil, nil
+	}
+	for i := len(e.middlewares) - 1; i >= 0; i-- {
+		endpoint = e.middlewares[i](endpoint)
+	}
+
+	_, err := endpoint(auth.RequestFunc(ctx, r), nil)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	return nil
+}
+
+func writeEvent(w io.Writer, id int, event string, data interface{}) error {
+	b, err := json.Marshal(data)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	_, err = fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", id, event, b)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	return nil
+}
@@ -0,0 +1,134 @@
+package stream
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/giantswarm/apiextensions/pkg/apis/core/v1alpha1"
+	"github.com/giantswarm/apiextensions/pkg/clientset/versioned/fake"
+	"github.com/giantswarm/micrologger/microloggertest"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	deferrerendpoint "github.com/giantswarm/shutdown-deferrer/server/endpoint/deferrer"
+	"github.com/giantswarm/shutdown-deferrer/service/deferrer"
+)
+
+func Test_Endpoint_ServeHTTP(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	g8sClient := fake.NewSimpleClientset()
+
+	provider, err := deferrer.NewDrainerConfigProvider(deferrer.DrainerConfigProviderConfig{
+		G8sClient: g8sClient,
+		Logger:    microloggertest.New(),
+	})
+	if err != nil {
+		t.Fatalf("error == %#v, want nil", err)
+	}
+
+	deferrerService, err := deferrer.New(deferrer.Config{
+		Logger:    microloggertest.New(),
+		Pod:       deferrer.Pod{Name: "foo", Namespace: "bar"},
+		Providers: []deferrer.Provider{provider},
+	})
+	if err != nil {
+		t.Fatalf("error == %#v, want nil", err)
+	}
+
+	err = deferrerService.Boot(ctx)
+	if err != nil {
+		t.Fatalf("error == %#v, want nil", err)
+	}
+
+	e, err := New(Config{
+		Deferrer: deferrerService,
+		Logger:   microloggertest.New(),
+	})
+	if err != nil {
+		t.Fatalf("error == %#v, want nil", err)
+	}
+
+	server := httptest.NewServer(e)
+	defer server.Close()
+
+	res, err := http.Get(server.URL)
+	if err != nil {
+		t.Fatalf("error == %#v, want nil", err)
+	}
+	defer res.Body.Close()
+
+	if res.Header.Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("Content-Type == %q, want %q", res.Header.Get("Content-Type"), "text/event-stream")
+	}
+
+	events := make(chan deferrerendpoint.Response)
+	go func() {
+		scanner := bufio.NewScanner(res.Body)
+		for scanner.Scan() {
+			line := scanner.Text()
+			if !strings.HasPrefix(line, "data: ") {
+				continue
+			}
+
+			var r deferrerendpoint.Response
+			err := json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &r)
+			if err != nil {
+				continue
+			}
+
+			select {
+			case events <- r:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	next := func() deferrerendpoint.Response {
+		select {
+		case r := <-events:
+			return r
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out waiting for event")
+			return deferrerendpoint.Response{}
+		}
+	}
+
+	r := next()
+	if !r.Defer || r.Reason != deferrer.ReasonNoDrainerConfig {
+		t.Fatalf("event == %#v, want deferring decision with reason %q", r, deferrer.ReasonNoDrainerConfig)
+	}
+
+	drainerConfig := &v1alpha1.DrainerConfig{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "foo",
+			Namespace: "bar",
+		},
+		Status: v1alpha1.DrainerConfigStatus{
+			Conditions: []v1alpha1.DrainerConfigStatusCondition{
+				{
+					LastTransitionTime: v1alpha1.DeepCopyTime{Time: time.Now()},
+					Status:             v1alpha1.DrainerConfigStatusStatusTrue,
+					Type:               v1alpha1.DrainerConfigStatusTypeDrained,
+				},
+			},
+		},
+	}
+
+	_, err = g8sClient.CoreV1alpha1().DrainerConfigs("bar").Create(drainerConfig)
+	if err != nil {
+		t.Fatalf("error == %#v, want nil", err)
+	}
+
+	r = next()
+	if r.Defer || r.Reason != deferrer.ReasonDrained {
+		t.Fatalf("event == %#v, want allowing decision with reason %q", r, deferrer.ReasonDrained)
+	}
+}
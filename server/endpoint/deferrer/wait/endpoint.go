@@ -0,0 +1,181 @@
+package wait
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/giantswarm/microerror"
+	"github.com/giantswarm/micrologger"
+	kitendpoint "github.com/go-kit/kit/endpoint"
+	kithttp "github.com/go-kit/kit/transport/http"
+
+	"github.com/giantswarm/shutdown-deferrer/service/audit"
+	"github.com/giantswarm/shutdown-deferrer/service/deferrer"
+)
+
+const (
+	// Method is the HTTP method this endpoint is registered for.
+	Method = "GET"
+	// Name identifies the endpoint. It is aligned to the package path.
+	Name = "deferrer/wait"
+	// Path is the HTTP request path this endpoint is registered for.
+	Path = "/v1/defer/wait"
+)
+
+const (
+	// DefaultTimeout is the time a request is held open when no timeout query
+	// parameter is given. It matches the poll timeout of pre-shutdown-hook.
+	DefaultTimeout = 120 * time.Second
+	// MaxTimeout is the upper bound for the timeout query parameter.
+	MaxTimeout = 10 * time.Minute
+)
+
+// Config represents the configuration used to create a wait endpoint.
+type Config struct {
+	// Dependencies.
+	Audit    *audit.Logger
+	Deferrer *deferrer.Service
+	Logger   micrologger.Logger
+
+	// Middlewares are applied to the endpoint, e.g. to authenticate requests.
+	Middlewares []kitendpoint.Middleware
+}
+
+type Endpoint struct {
+	audit    *audit.Logger
+	deferrer *deferrer.Service
+	logger   micrologger.Logger
+
+	middlewares []kitendpoint.Middleware
+}
+
+// Request is the decoded request of the wait endpoint.
+type Request struct {
+	// Caller identifies the client for the audit trail.
+	Caller audit.Caller
+	// Done is closed when the client connection goes away.
+	Done <-chan struct{}
+	// Start is the time the request was received.
+	Start time.Time
+	// Timeout is the maximum time the request is held open.
+	Timeout time.Duration
+}
+
+// New creates a new configured wait endpoint.
+func New(config Config) (*Endpoint, error) {
+	// Dependencies.
+	if config.Audit == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Audit must not be empty", config)
+	}
+	if config.Deferrer == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Deferrer must not be empty", config)
+	}
+	if config.Logger == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Logger must not be empty", config)
+	}
+
+	e := &Endpoint{
+		audit:    config.Audit,
+		deferrer: config.Deferrer,
+		logger:   config.Logger,
+
+		middlewares: config.Middlewares,
+	}
+
+	return e, nil
+}
+
+func (e *Endpoint) Decoder() kithttp.DecodeRequestFunc {
+	return func(ctx context.Context, r *http.Request) (interface{}, error) {
+		request := Request{
+			Caller:  audit.NewCaller(r),
+			Done:    r.Context().Done(),
+			Start:   time.Now(),
+			Timeout: DefaultTimeout,
+		}
+
+		if t := r.URL.Query().Get("timeout"); t != "" {
+			timeout, err := time.ParseDuration(t)
+			if err != nil {
+				return nil, microerror.Maskf(invalidRequestError, "timeout must be a duration like 120s: %s", err)
+			}
+			if timeout <= 0 || timeout > MaxTimeout {
+				return nil, microerror.Maskf(invalidRequestError, "timeout must be greater than 0s and at most %s", MaxTimeout)
+			}
+
+			request.Timeout = timeout
+		}
+
+		return request, nil
+	}
+}
+
+func (e *Endpoint) Encoder() kithttp.EncodeResponseFunc {
+	return func(ctx context.Context, w http.ResponseWriter, response interface{}) error {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.WriteHeader(http.StatusOK)
+		b, ok := response.([]byte)
+		if !ok {
+			return microerror.Mask(invalidResponseTypeError)
+		}
+		_, err := w.Write(b)
+		return err
+	}
+}
+
+func (e *Endpoint) Endpoint() kitendpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		r, ok := request.(Request)
+		if !ok {
+			return nil, microerror.Maskf(invalidRequestError, "expected %T, got %T", r, request)
+		}
+
+		ctx, cancel := context.WithTimeout(ctx, r.Timeout)
+		defer cancel()
+
+		// The request context provided by microkit is not bound to the client
+		// connection, so we stop waiting ourselves once the client went away.
+		go func() {
+			select {
+			case <-r.Done:
+				cancel()
+			case <-ctx.Done():
+			}
+		}()
+
+		decision, err := e.deferrer.Wait(ctx)
+		e.writeAudit(ctx, audit.NewEntry(Name, r.Caller, r.Start, decision, err))
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+		return []byte(fmt.Sprintf("%t", decision.Defer)), nil
+	}
+}
+
+func (e *Endpoint) Method() string {
+	return Method
+}
+
+// Middlewares returns a slice of the middlewares used in this endpoint.
+func (e *Endpoint) Middlewares() []kitendpoint.Middleware {
+	return e.middlewares
+}
+
+func (e *Endpoint) Name() string {
+	return Name
+}
+
+func (e *Endpoint) Path() string {
+	return Path
+}
+
+// writeAudit writes the given entry to the audit trail. Failures are logged
+// but do not fail the query.
+func (e *Endpoint) writeAudit(ctx context.Context, entry audit.Entry) {
+	err := e.audit.Log(entry)
+	if err != nil {
+		_ = e.logger.LogCtx(ctx, "level", "warning", "message", "failed to write audit trail", "stack", fmt.Sprintf("%#v", err))
+	}
+}
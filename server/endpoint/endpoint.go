@@ -5,20 +5,39 @@ import (
 	"github.com/giantswarm/microendpoint/endpoint/version"
 	"github.com/giantswarm/microerror"
 	"github.com/giantswarm/micrologger"
+	kitendpoint "github.com/go-kit/kit/endpoint"
 
 	"github.com/giantswarm/shutdown-deferrer/server/endpoint/deferrer"
+	"github.com/giantswarm/shutdown-deferrer/server/endpoint/deferrer/override"
+	"github.com/giantswarm/shutdown-deferrer/server/endpoint/deferrer/pod"
+	"github.com/giantswarm/shutdown-deferrer/server/endpoint/deferrer/stream"
+	"github.com/giantswarm/shutdown-deferrer/server/endpoint/deferrer/wait"
+	"github.com/giantswarm/shutdown-deferrer/server/endpoint/readyz"
 	"github.com/giantswarm/shutdown-deferrer/service"
 )
 
 type Config struct {
 	Logger  micrologger.Logger
 	Service *service.Service
+
+	// DeferrerMiddlewares are applied to the deferrer endpoints, e.g. to
+	// authenticate defer queries.
+	DeferrerMiddlewares []kitendpoint.Middleware
+	// OverrideMiddlewares are applied to the override endpoints. They must
+	// authenticate requests.
+	OverrideMiddlewares []kitendpoint.Middleware
 }
 
 type Endpoint struct {
-	Deferrer *deferrer.Endpoint
-	Healthz  *healthz.Endpoint
-	Version  *version.Endpoint
+	Deferrer               *deferrer.Endpoint
+	DeferrerOverrideDelete *override.Endpoint
+	DeferrerOverridePut    *override.Endpoint
+	DeferrerPod            *pod.Endpoint
+	DeferrerStream         *stream.Endpoint
+	DeferrerWait           *wait.Endpoint
+	Healthz                *healthz.Endpoint
+	Readyz                 *readyz.Endpoint
+	Version                *version.Endpoint
 }
 
 func New(config Config) (*Endpoint, error) {
@@ -27,8 +46,11 @@ func New(config Config) (*Endpoint, error) {
 	var deferrerEndpoint *deferrer.Endpoint
 	{
 		c := deferrer.Config{
+			Audit:    config.Service.Audit,
 			Deferrer: config.Service.Deferrer,
 			Logger:   config.Logger,
+
+			Middlewares: config.DeferrerMiddlewares,
 		}
 
 		deferrerEndpoint, err = deferrer.New(c)
@@ -37,6 +59,85 @@ func New(config Config) (*Endpoint, error) {
 		}
 	}
 
+	var deferrerOverrideDeleteEndpoint *override.Endpoint
+	{
+		c := override.Config{
+			Deferrer: config.Service.Deferrer,
+			Logger:   config.Logger,
+
+			Method:      override.MethodDelete,
+			Middlewares: config.OverrideMiddlewares,
+		}
+
+		deferrerOverrideDeleteEndpoint, err = override.New(c)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+	}
+
+	var deferrerOverridePutEndpoint *override.Endpoint
+	{
+		c := override.Config{
+			Deferrer: config.Service.Deferrer,
+			Logger:   config.Logger,
+
+			Method:      override.MethodPut,
+			Middlewares: config.OverrideMiddlewares,
+		}
+
+		deferrerOverridePutEndpoint, err = override.New(c)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+	}
+
+	var deferrerPodEndpoint *pod.Endpoint
+	{
+		c := pod.Config{
+			Audit:    config.Service.Audit,
+			Deferrer: config.Service.Deferrer,
+			Logger:   config.Logger,
+
+			Middlewares: config.DeferrerMiddlewares,
+		}
+
+		deferrerPodEndpoint, err = pod.New(c)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+	}
+
+	var deferrerStreamEndpoint *stream.Endpoint
+	{
+		c := stream.Config{
+			Deferrer: config.Service.Deferrer,
+			Logger:   config.Logger,
+
+			Middlewares: config.DeferrerMiddlewares,
+		}
+
+		deferrerStreamEndpoint, err = stream.New(c)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+	}
+
+	var deferrerWaitEndpoint *wait.Endpoint
+	{
+		c := wait.Config{
+			Audit:    config.Service.Audit,
+			Deferrer: config.Service.Deferrer,
+			Logger:   config.Logger,
+
+			Middlewares: config.DeferrerMiddlewares,
+		}
+
+		deferrerWaitEndpoint, err = wait.New(c)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+	}
+
 	var healthzEndpoint *healthz.Endpoint
 	{
 		c := healthz.Config{
@@ -49,6 +150,19 @@ func New(config Config) (*Endpoint, error) {
 		}
 	}
 
+	var readyzEndpoint *readyz.Endpoint
+	{
+		c := readyz.Config{
+			Logger:   config.Logger,
+			Services: config.Service.Readiness,
+		}
+
+		readyzEndpoint, err = readyz.New(c)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+	}
+
 	var versionEndpoint *version.Endpoint
 	{
 		c := version.Config{
@@ -63,9 +177,15 @@ func New(config Config) (*Endpoint, error) {
 	}
 
 	e := &Endpoint{
-		Deferrer: deferrerEndpoint,
-		Healthz:  healthzEndpoint,
-		Version:  versionEndpoint,
+		Deferrer:               deferrerEndpoint,
+		DeferrerOverrideDelete: deferrerOverrideDeleteEndpoint,
+		DeferrerOverridePut:    deferrerOverridePutEndpoint,
+		DeferrerPod:            deferrerPodEndpoint,
+		DeferrerStream:         deferrerStreamEndpoint,
+		DeferrerWait:           deferrerWaitEndpoint,
+		Healthz:                healthzEndpoint,
+		Readyz:                 readyzEndpoint,
+		Version:                versionEndpoint,
 	}
 
 	return e, nil
@@ -0,0 +1,113 @@
+package readyz
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/giantswarm/microendpoint/service/healthz"
+	"github.com/giantswarm/microerror"
+	"github.com/giantswarm/micrologger"
+	kitendpoint "github.com/go-kit/kit/endpoint"
+	kithttp "github.com/go-kit/kit/transport/http"
+)
+
+const (
+	// Method is the HTTP method this endpoint is registered for.
+	Method = "GET"
+	// Name identifies the endpoint. It is aligned to the package path.
+	Name = "readyz"
+	// Path is the HTTP request path this endpoint is registered for.
+	Path = "/readyz"
+)
+
+// Config represents the configuration used to create a readyz endpoint.
+type Config struct {
+	// Dependencies.
+	Logger   micrologger.Logger
+	Services []healthz.Service
+}
+
+// Endpoint reports whether the deferrer is able to make decisions. Unlike
+// healthz it responds with 503 when any check failed, so that it can back a
+// readiness probe without restarting the POD.
+type Endpoint struct {
+	logger   micrologger.Logger
+	services []healthz.Service
+}
+
+// New creates a new configured readyz endpoint.
+func New(config Config) (*Endpoint, error) {
+	// Dependencies.
+	if config.Logger == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Logger must not be empty", config)
+	}
+
+	e := &Endpoint{
+		logger:   config.Logger,
+		services: config.Services,
+	}
+
+	return e, nil
+}
+
+func (e *Endpoint) Decoder() kithttp.DecodeRequestFunc {
+	return func(ctx context.Context, r *http.Request) (interface{}, error) {
+		return nil, nil
+	}
+}
+
+func (e *Endpoint) Encoder() kithttp.EncodeResponseFunc {
+	return func(ctx context.Context, w http.ResponseWriter, response interface{}) error {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+
+		rs, ok := response.([]healthz.Response)
+		if !ok {
+			return microerror.Maskf(wrongTypeError, "expected '%T' got '%T'", []healthz.Response{}, response)
+		}
+		if healthz.Responses(rs).HasFailed() {
+			for _, r := range rs {
+				if r.Failed {
+					_ = e.logger.LogCtx(ctx, "level", "warning", "message", "readiness check failed", "check", r.Name, "reason", r.Message)
+				}
+			}
+
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+
+		return json.NewEncoder(w).Encode(rs)
+	}
+}
+
+func (e *Endpoint) Endpoint() kitendpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		responses := []healthz.Response{}
+
+		for _, s := range e.services {
+			r, err := s.GetHealthz(ctx)
+			if err != nil {
+				return nil, microerror.Mask(err)
+			}
+
+			responses = append(responses, r)
+		}
+
+		return responses, nil
+	}
+}
+
+func (e *Endpoint) Method() string {
+	return Method
+}
+
+func (e *Endpoint) Middlewares() []kitendpoint.Middleware {
+	return []kitendpoint.Middleware{}
+}
+
+func (e *Endpoint) Name() string {
+	return Name
+}
+
+func (e *Endpoint) Path() string {
+	return Path
+}
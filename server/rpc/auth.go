@@ -0,0 +1,103 @@
+package rpc
+
+import (
+	"context"
+	"strings"
+
+	kitendpoint "github.com/go-kit/kit/endpoint"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+
+	"github.com/giantswarm/shutdown-deferrer/server/auth"
+)
+
+// healthServicePrefix is the method prefix of the health service, which is not
+// authenticated, so that probes do not need credentials.
+const healthServicePrefix = "/grpc.health.v1.Health/"
+
+// newUnaryAuthInterceptor returns an interceptor authenticating unary calls
+// using the given middleware, like the HTTP endpoints.
+func newUnaryAuthInterceptor(middleware kitendpoint.Middleware) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if strings.HasPrefix(info.FullMethod, healthServicePrefix) {
+			return handler(ctx, req)
+		}
+
+		endpoint := middleware(func(ctx context.Context, request interface{}) (interface{}, error) {
+			return handler(ctx, request)
+		})
+
+		response, err := endpoint(newAuthContext(ctx), req)
+		if auth.IsUnauthenticated(err) {
+			return nil, status.Error(codes.Unauthenticated, "authentication required")
+		} else if err != nil {
+			return nil, err
+		}
+
+		return response, nil
+	}
+}
+
+// newStreamAuthInterceptor returns an interceptor authenticating streaming
+// calls using the given middleware, like the HTTP endpoints.
+func newStreamAuthInterceptor(middleware kitendpoint.Middleware) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if strings.HasPrefix(info.FullMethod, healthServicePrefix) {
+			return handler(srv, ss)
+		}
+
+		endpoint := middleware(func(ctx context.Context, request interface{}) (interface{}, error) {
+			return nil, handler(srv, &serverStream{ServerStream: ss, ctx: ctx})
+		})
+
+		_, err := endpoint(newAuthContext(ss.Context()), nil)
+		if auth.IsUnauthenticated(err) {
+			return status.Error(codes.Unauthenticated, "authentication required")
+		} else if err != nil {
+			return err
+		}
+
+		return nil
+	}
+}
+
+// newAuthContext puts the bearer token of the authorization metadata and the
+// verified client certificates of the call into the given context.
+func newAuthContext(ctx context.Context) context.Context {
+	var c auth.Credentials
+
+	const prefix = "Bearer "
+	md, ok := metadata.FromIncomingContext(ctx)
+	if ok {
+		for _, v := range md.Get("authorization") {
+			if len(v) > len(prefix) && strings.EqualFold(v[:len(prefix)], prefix) {
+				c.BearerToken = strings.TrimSpace(v[len(prefix):])
+			}
+		}
+	}
+
+	p, ok := peer.FromContext(ctx)
+	if ok {
+		tlsInfo, ok := p.AuthInfo.(credentials.TLSInfo)
+		if ok {
+			c.PeerCertificates = tlsInfo.State.PeerCertificates
+		}
+	}
+
+	return auth.NewContext(ctx, c)
+}
+
+// serverStream replaces the context of a stream with the one carrying the
+// authenticated user.
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
@@ -0,0 +1,133 @@
+package rpc
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/giantswarm/micrologger"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+
+	"github.com/giantswarm/shutdown-deferrer/pkg/deferrerpb"
+	"github.com/giantswarm/shutdown-deferrer/service/audit"
+	"github.com/giantswarm/shutdown-deferrer/service/deferrer"
+)
+
+const (
+	// auditEndpoint identifies calls of ShouldDefer in the audit trail.
+	auditEndpoint = "grpc/ShouldDefer"
+)
+
+type deferrerServer struct {
+	audit    *audit.Logger
+	deferrer *deferrer.Service
+	logger   micrologger.Logger
+}
+
+func (s *deferrerServer) ShouldDefer(ctx context.Context, request *deferrerpb.ShouldDeferRequest) (*deferrerpb.Decision, error) {
+	start := time.Now()
+
+	decision, err := s.deferrer.Decide(ctx)
+
+	auditErr := s.audit.Log(audit.NewEntry(auditEndpoint, newCaller(ctx), start, decision, err))
+	if auditErr != nil {
+		_ = s.logger.LogCtx(ctx, "level", "warning", "message", "failed to write audit trail", "stack", fmt.Sprintf("%#v", auditErr))
+	}
+
+	if err != nil {
+		return nil, newStatusError(err)
+	}
+
+	return newDecision(decision), nil
+}
+
+// WatchDefer sends the current decision and every decision which differs from
+// the previous one until the client goes away or deciding fails.
+func (s *deferrerServer) WatchDefer(request *deferrerpb.WatchDeferRequest, stream deferrerpb.Deferrer_WatchDeferServer) error {
+	var last *deferrerpb.Decision
+	var sendErr error
+
+	err := s.deferrer.Watch(stream.Context(), func(decision deferrer.Decision) bool {
+		d := newDecision(decision)
+		if last != nil && reflect.DeepEqual(last, d) {
+			return true
+		}
+		last = d
+
+		sendErr = stream.Send(d)
+		return sendErr == nil
+	})
+	if err != nil {
+		return newStatusError(err)
+	}
+	if sendErr != nil {
+		return sendErr
+	}
+
+	return nil
+}
+
+func newCaller(ctx context.Context) audit.Caller {
+	var caller audit.Caller
+
+	p, ok := peer.FromContext(ctx)
+	if ok && p.Addr != nil {
+		caller.RemoteAddr = p.Addr.String()
+	}
+
+	md, ok := metadata.FromIncomingContext(ctx)
+	if ok && len(md.Get("user-agent")) != 0 {
+		caller.UserAgent = md.Get("user-agent")[0]
+	}
+
+	return caller
+}
+
+func newDecision(decision deferrer.Decision) *deferrerpb.Decision {
+	d := &deferrerpb.Decision{
+		Defer:    decision.Defer,
+		Reason:   decision.Reason,
+		Provider: decision.Provider,
+
+		Error: decision.Error,
+	}
+
+	if decision.DrainerConfig != nil {
+		d.DrainerConfig = &deferrerpb.DrainerConfig{
+			Name:            decision.DrainerConfig.Name,
+			Namespace:       decision.DrainerConfig.Namespace,
+			ResourceVersion: decision.DrainerConfig.ResourceVersion,
+		}
+	}
+
+	if decision.Condition != nil {
+		d.Condition = &deferrerpb.Condition{
+			LastTransitionTime: decision.Condition.LastTransitionTime.UTC().Format(time.RFC3339),
+			Status:             decision.Condition.Status,
+			Type:               decision.Condition.Type,
+		}
+	}
+
+	if decision.Override != nil {
+		d.Override = &deferrerpb.Override{
+			Force:   decision.Override.Force,
+			Reason:  decision.Override.Reason,
+			Expires: decision.Override.Expires.UTC().Format(time.RFC3339),
+			User:    decision.Override.User,
+		}
+	}
+
+	return d
+}
+
+func newStatusError(err error) error {
+	if deferrer.IsInvalidConfig(err) {
+		return status.Error(codes.FailedPrecondition, err.Error())
+	}
+
+	return status.Error(codes.Internal, err.Error())
+}
@@ -0,0 +1,47 @@
+package rpc
+
+import (
+	"context"
+
+	"github.com/giantswarm/microendpoint/service/healthz"
+	"google.golang.org/grpc/codes"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+
+	"github.com/giantswarm/shutdown-deferrer/pkg/deferrerpb"
+)
+
+// healthServer reports the result of the readiness checks, like /readyz does
+// for HTTP clients.
+type healthServer struct {
+	readiness []healthz.Service
+}
+
+// Check reports SERVING when all readiness checks pass. Only the overall
+// health, given as empty service name, and the Deferrer service are known.
+func (s *healthServer) Check(ctx context.Context, request *healthpb.HealthCheckRequest) (*healthpb.HealthCheckResponse, error) {
+	if request.Service != "" && request.Service != deferrerpb.ServiceName {
+		return nil, status.Errorf(codes.NotFound, "unknown service %q", request.Service)
+	}
+
+	var responses []healthz.Response
+	for _, r := range s.readiness {
+		response, err := r.GetHealthz(ctx)
+		if err != nil {
+			return nil, status.Error(codes.Internal, err.Error())
+		}
+
+		responses = append(responses, response)
+	}
+
+	if healthz.Responses(responses).HasFailed() {
+		return &healthpb.HealthCheckResponse{Status: healthpb.HealthCheckResponse_NOT_SERVING}, nil
+	}
+
+	return &healthpb.HealthCheckResponse{Status: healthpb.HealthCheckResponse_SERVING}, nil
+}
+
+// Watch is not supported. Clients fall back to polling Check.
+func (s *healthServer) Watch(request *healthpb.HealthCheckRequest, stream healthpb.Health_WatchServer) error {
+	return status.Error(codes.Unimplemented, "watching health is not supported")
+}
@@ -0,0 +1,139 @@
+// Package rpc implements the gRPC API of the shutdown-deferrer. It serves the
+// Deferrer service defined in pkg/deferrerpb, the standard gRPC health service
+// and server reflection next to the microkit HTTP server.
+package rpc
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/giantswarm/microendpoint/service/healthz"
+	"github.com/giantswarm/microerror"
+	"github.com/giantswarm/micrologger"
+	"google.golang.org/grpc"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
+
+	"github.com/giantswarm/shutdown-deferrer/pkg/deferrerpb"
+	"github.com/giantswarm/shutdown-deferrer/server/auth"
+	"github.com/giantswarm/shutdown-deferrer/service/audit"
+	"github.com/giantswarm/shutdown-deferrer/service/deferrer"
+)
+
+const (
+	// shutdownTimeout is the time running calls, e.g. WatchDefer streams, are
+	// given to finish when the server is stopped.
+	shutdownTimeout = 3 * time.Second
+)
+
+type Config struct {
+	Audit    *audit.Logger
+	Deferrer *deferrer.Service
+	Logger   micrologger.Logger
+	// Readiness are the checks determining the serving status reported by
+	// the health service.
+	Readiness []healthz.Service
+
+	// Address is the address the server listens on, e.g. :8001.
+	Address string
+	// Authenticators authenticate calls of the Deferrer service. Calls are
+	// not authenticated when none are given. Health checks are never
+	// authenticated.
+	Authenticators []auth.Authenticator
+}
+
+type Server struct {
+	logger micrologger.Logger
+
+	address    string
+	grpcServer *grpc.Server
+}
+
+func New(config Config) (*Server, error) {
+	if config.Audit == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Audit must not be empty", config)
+	}
+	if config.Deferrer == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Deferrer must not be empty", config)
+	}
+	if config.Logger == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Logger must not be empty", config)
+	}
+
+	if config.Address == "" {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Address must not be empty", config)
+	}
+
+	var options []grpc.ServerOption
+	if len(config.Authenticators) != 0 {
+		middleware := auth.NewMiddleware(config.Logger, config.Authenticators)
+
+		options = append(options,
+			grpc.UnaryInterceptor(newUnaryAuthInterceptor(middleware)),
+			grpc.StreamInterceptor(newStreamAuthInterceptor(middleware)),
+		)
+	}
+
+	grpcServer := grpc.NewServer(options...)
+
+	deferrerpb.RegisterDeferrerServer(grpcServer, &deferrerServer{
+		audit:    config.Audit,
+		deferrer: config.Deferrer,
+		logger:   config.Logger,
+	})
+	healthpb.RegisterHealthServer(grpcServer, &healthServer{
+		readiness: config.Readiness,
+	})
+	// Reflection lets clients like grpcurl discover the services without
+	// deferrer.proto.
+	reflection.Register(grpcServer)
+
+	s := &Server{
+		logger: config.Logger,
+
+		address:    config.Address,
+		grpcServer: grpcServer,
+	}
+
+	return s, nil
+}
+
+// Boot starts listening on the configured address. It returns once the
+// listener is created, serving happens in the background.
+func (s *Server) Boot() error {
+	listener, err := net.Listen("tcp", s.address)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	go func() {
+		ctx := context.Background()
+
+		_ = s.logger.LogCtx(ctx, "level", "debug", "message", fmt.Sprintf("running grpc server at %s", listener.Addr()))
+
+		err := s.grpcServer.Serve(listener)
+		if err != nil {
+			_ = s.logger.LogCtx(ctx, "level", "error", "message", "failed to run grpc server", "stack", fmt.Sprintf("%#v", err))
+		}
+	}()
+
+	return nil
+}
+
+// Stop gracefully stops the server. Calls still running after the shutdown
+// timeout are cancelled.
+func (s *Server) Stop() {
+	stopped := make(chan struct{})
+	go func() {
+		s.grpcServer.GracefulStop()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+	case <-time.After(shutdownTimeout):
+		s.grpcServer.Stop()
+	}
+}
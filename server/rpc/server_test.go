@@ -0,0 +1,176 @@
+package rpc
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/giantswarm/apiextensions/pkg/apis/core/v1alpha1"
+	"github.com/giantswarm/apiextensions/pkg/clientset/versioned/fake"
+	"github.com/giantswarm/micrologger/microloggertest"
+	"google.golang.org/grpc"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	reflectionpb "google.golang.org/grpc/reflection/grpc_reflection_v1alpha"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/giantswarm/shutdown-deferrer/pkg/deferrerpb"
+	"github.com/giantswarm/shutdown-deferrer/service/audit"
+	"github.com/giantswarm/shutdown-deferrer/service/deferrer"
+)
+
+func Test_Server(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	g8sClient := fake.NewSimpleClientset()
+
+	provider, err := deferrer.NewDrainerConfigProvider(deferrer.DrainerConfigProviderConfig{
+		G8sClient: g8sClient,
+		Logger:    microloggertest.New(),
+	})
+	if err != nil {
+		t.Fatalf("error == %#v, want nil", err)
+	}
+
+	deferrerService, err := deferrer.New(deferrer.Config{
+		Logger:    microloggertest.New(),
+		Pod:       deferrer.Pod{Name: "foo", Namespace: "bar"},
+		Providers: []deferrer.Provider{provider},
+	})
+	if err != nil {
+		t.Fatalf("error == %#v, want nil", err)
+	}
+
+	err = deferrerService.Boot(ctx)
+	if err != nil {
+		t.Fatalf("error == %#v, want nil", err)
+	}
+
+	auditLogger, err := audit.New(audit.Config{})
+	if err != nil {
+		t.Fatalf("error == %#v, want nil", err)
+	}
+
+	s, err := New(Config{
+		Audit:    auditLogger,
+		Deferrer: deferrerService,
+		Logger:   microloggertest.New(),
+
+		Address: "127.0.0.1:0",
+	})
+	if err != nil {
+		t.Fatalf("error == %#v, want nil", err)
+	}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("error == %#v, want nil", err)
+	}
+	go func() {
+		_ = s.grpcServer.Serve(listener)
+	}()
+	defer s.Stop()
+
+	conn, err := grpc.DialContext(ctx, listener.Addr().String(), grpc.WithInsecure(), grpc.WithBlock())
+	if err != nil {
+		t.Fatalf("error == %#v, want nil", err)
+	}
+	defer conn.Close()
+
+	client := deferrerpb.NewDeferrerClient(conn)
+
+	{
+		d, err := client.ShouldDefer(ctx, &deferrerpb.ShouldDeferRequest{})
+		if err != nil {
+			t.Fatalf("error == %#v, want nil", err)
+		}
+		if !d.Defer || d.Reason != deferrer.ReasonNoDrainerConfig {
+			t.Fatalf("decision == %#v, want deferring decision with reason %q", d, deferrer.ReasonNoDrainerConfig)
+		}
+	}
+
+	{
+		stream, err := client.WatchDefer(ctx, &deferrerpb.WatchDeferRequest{})
+		if err != nil {
+			t.Fatalf("error == %#v, want nil", err)
+		}
+
+		d, err := stream.Recv()
+		if err != nil {
+			t.Fatalf("error == %#v, want nil", err)
+		}
+		if !d.Defer || d.Reason != deferrer.ReasonNoDrainerConfig {
+			t.Fatalf("decision == %#v, want deferring decision with reason %q", d, deferrer.ReasonNoDrainerConfig)
+		}
+
+		drainerConfig := &v1alpha1.DrainerConfig{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "foo",
+				Namespace: "bar",
+			},
+			Status: v1alpha1.DrainerConfigStatus{
+				Conditions: []v1alpha1.DrainerConfigStatusCondition{
+					{
+						LastTransitionTime: v1alpha1.DeepCopyTime{Time: time.Now()},
+						Status:             v1alpha1.DrainerConfigStatusStatusTrue,
+						Type:               v1alpha1.DrainerConfigStatusTypeDrained,
+					},
+				},
+			},
+		}
+		_, err = g8sClient.CoreV1alpha1().DrainerConfigs("bar").Create(drainerConfig)
+		if err != nil {
+			t.Fatalf("error == %#v, want nil", err)
+		}
+
+		d, err = stream.Recv()
+		if err != nil {
+			t.Fatalf("error == %#v, want nil", err)
+		}
+		if d.Defer || d.Reason != deferrer.ReasonDrained {
+			t.Fatalf("decision == %#v, want allowing decision with reason %q", d, deferrer.ReasonDrained)
+		}
+		if d.DrainerConfig == nil || d.DrainerConfig.Name != "foo" {
+			t.Fatalf("decision.DrainerConfig == %#v, want DrainerConfig foo", d.DrainerConfig)
+		}
+	}
+
+	{
+		client := healthpb.NewHealthClient(conn)
+
+		r, err := client.Check(ctx, &healthpb.HealthCheckRequest{})
+		if err != nil {
+			t.Fatalf("error == %#v, want nil", err)
+		}
+		if r.Status != healthpb.HealthCheckResponse_SERVING {
+			t.Fatalf("status == %s, want %s", r.Status, healthpb.HealthCheckResponse_SERVING)
+		}
+	}
+
+	{
+		client := reflectionpb.NewServerReflectionClient(conn)
+
+		stream, err := client.ServerReflectionInfo(ctx)
+		if err != nil {
+			t.Fatalf("error == %#v, want nil", err)
+		}
+
+		err = stream.Send(&reflectionpb.ServerReflectionRequest{
+			MessageRequest: &reflectionpb.ServerReflectionRequest_FileContainingSymbol{
+				FileContainingSymbol: deferrerpb.ServiceName,
+			},
+		})
+		if err != nil {
+			t.Fatalf("error == %#v, want nil", err)
+		}
+
+		r, err := stream.Recv()
+		if err != nil {
+			t.Fatalf("error == %#v, want nil", err)
+		}
+		if len(r.GetFileDescriptorResponse().GetFileDescriptorProto()) == 0 {
+			t.Fatalf("response == %#v, want file descriptor of %s", r, deferrerpb.ServiceName)
+		}
+	}
+}
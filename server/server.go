@@ -2,16 +2,32 @@ package server
 
 import (
 	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
 	"net/http"
+	"os"
+	"strconv"
 	"sync"
+	"syscall"
+	"time"
 
 	"github.com/giantswarm/microerror"
 	microserver "github.com/giantswarm/microkit/server"
 	"github.com/giantswarm/micrologger"
+	kitendpoint "github.com/go-kit/kit/endpoint"
+	kithttp "github.com/go-kit/kit/transport/http"
+	"github.com/gorilla/mux"
 	"github.com/spf13/viper"
 
+	"github.com/giantswarm/shutdown-deferrer/server/auth"
 	"github.com/giantswarm/shutdown-deferrer/server/endpoint"
+	"github.com/giantswarm/shutdown-deferrer/server/endpoint/deferrer"
+	"github.com/giantswarm/shutdown-deferrer/server/endpoint/deferrer/override"
+	"github.com/giantswarm/shutdown-deferrer/server/endpoint/deferrer/wait"
+	"github.com/giantswarm/shutdown-deferrer/server/rpc"
 	"github.com/giantswarm/shutdown-deferrer/service"
+	servicedeferrer "github.com/giantswarm/shutdown-deferrer/service/deferrer"
 )
 
 // Config represents the configuration used to construct server object.
@@ -20,28 +36,178 @@ type Config struct {
 	Service *service.Service
 	Viper   *viper.Viper
 
+	// Auth configures the authentication of defer queries. Defer queries are
+	// not authenticated when no method is configured. Overrides are always
+	// authenticated and rejected when no method is configured.
+	Auth AuthConfig
+	// DrainPeriod is the maximum time the server keeps serving after it has
+	// been asked to shut down, while waiting for the final decision allowing
+	// node termination.
+	DrainPeriod time.Duration
+	// GRPC configures the gRPC server serving the Deferrer and health
+	// services next to the HTTP endpoints.
+	GRPC        GRPCConfig
 	ProjectName string
+	// Socket configures an additional listener serving all endpoints on a
+	// Unix domain socket.
+	Socket SocketConfig
+}
+
+// AuthConfig configures the authentication methods of defer queries. A query
+// is authenticated when any configured method succeeds.
+type AuthConfig struct {
+	// MTLS configures an additional TLS listener requiring client
+	// certificates signed by the given CA.
+	MTLS AuthConfigMTLS
+	// TokenFile contains a shared bearer token clients must present.
+	TokenFile string
+	// TokenReview enables authenticating service account tokens using the
+	// Kubernetes TokenReview API.
+	TokenReview bool
+	// TokenReviewAudiences are the audiences reviewed tokens must be valid
+	// for.
+	TokenReviewAudiences []string
+}
+
+type AuthConfigMTLS struct {
+	Address string
+	CAFile  string
+	CrtFile string
+	KeyFile string
+}
+
+// GRPCConfig configures the gRPC server. It is disabled when no address is
+// given. Calls are authenticated like defer queries.
+type GRPCConfig struct {
+	Address string
+}
+
+// SocketConfig configures the Unix domain socket listener. It is disabled when
+// no path is given.
+type SocketConfig struct {
+	// Mode is the octal file mode of the socket, e.g. 0660.
+	Mode string
+	Path string
 }
 
 type Server struct {
 	// Dependencies.
 	logger micrologger.Logger
 
+	service *service.Service
+
 	// Internals.
-	bootOnce     sync.Once
 	config       microserver.Config
+	grpcServer   *rpc.Server
+	mtlsServer   *http.Server
 	shutdownOnce sync.Once
+	socketServer *http.Server
+	startErr     error
+	startOnce    sync.Once
+
+	// Settings.
+	drainPeriod time.Duration
+	socketMode  os.FileMode
+	socketPath  string
 }
 
 // New creates a new configured server object.
 func New(config Config) (*Server, error) {
 	var err error
 
+	var authenticators []auth.Authenticator
+	if config.Auth.TokenFile != "" {
+		c := auth.TokenAuthenticatorConfig{
+			File: config.Auth.TokenFile,
+		}
+
+		a, err := auth.NewTokenAuthenticator(c)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+
+		authenticators = append(authenticators, a)
+	}
+	if config.Auth.TokenReview {
+		c := auth.TokenReviewAuthenticatorConfig{
+			K8sClient: config.Service.K8sClient,
+
+			Audiences: config.Auth.TokenReviewAudiences,
+		}
+
+		a, err := auth.NewTokenReviewAuthenticator(c)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+
+		authenticators = append(authenticators, a)
+	}
+
+	router := mux.NewRouter()
+
+	var mtlsServer *http.Server
+	if config.Auth.MTLS.CAFile != "" {
+		if config.Auth.MTLS.Address == "" {
+			return nil, microerror.Maskf(invalidConfigError, "%T.Auth.MTLS.Address must not be empty", config)
+		}
+		if config.Auth.MTLS.CrtFile == "" || config.Auth.MTLS.KeyFile == "" {
+			return nil, microerror.Maskf(invalidConfigError, "%T.Auth.MTLS.CrtFile and %T.Auth.MTLS.KeyFile must not be empty", config, config)
+		}
+
+		c := auth.ClientCertAuthenticatorConfig{
+			CAFile: config.Auth.MTLS.CAFile,
+		}
+
+		a, err := auth.NewClientCertAuthenticator(c)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+
+		authenticators = append(authenticators, a)
+
+		pool, err := auth.LoadCertPool(config.Auth.MTLS.CAFile)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+
+		cert, err := tls.LoadX509KeyPair(config.Auth.MTLS.CrtFile, config.Auth.MTLS.KeyFile)
+		if err != nil {
+			return nil, microerror.Maskf(invalidConfigError, "%s", err)
+		}
+
+		// The listener serves the router microkit registers all endpoints to.
+		// The handshake requires a client certificate signed by the CA on all
+		// endpoints, the middleware of the deferrer endpoints verifies it
+		// again for queries received on other listeners.
+		mtlsServer = &http.Server{
+			Addr:    config.Auth.MTLS.Address,
+			Handler: router,
+			TLSConfig: &tls.Config{
+				Certificates: []tls.Certificate{cert},
+				ClientAuth:   tls.RequireAndVerifyClientCert,
+				ClientCAs:    pool,
+				MinVersion:   tls.VersionTLS12,
+			},
+		}
+	}
+
+	var deferrerMiddlewares []kitendpoint.Middleware
+	if len(authenticators) != 0 {
+		deferrerMiddlewares = append(deferrerMiddlewares, auth.NewMiddleware(config.Logger, authenticators))
+	}
+
+	overrideMiddlewares := []kitendpoint.Middleware{
+		auth.NewMiddleware(config.Logger, authenticators),
+	}
+
 	var endpointCollection *endpoint.Endpoint
 	{
 		c := endpoint.Config{
 			Logger:  config.Logger,
 			Service: config.Service,
+
+			DeferrerMiddlewares: deferrerMiddlewares,
+			OverrideMiddlewares: overrideMiddlewares,
 		}
 
 		endpointCollection, err = endpoint.New(c)
@@ -50,48 +216,271 @@ func New(config Config) (*Server, error) {
 		}
 	}
 
+	var socketMode os.FileMode
+	var socketServer *http.Server
+	if config.Socket.Path != "" {
+		m, err := strconv.ParseUint(config.Socket.Mode, 8, 32)
+		if err != nil || os.FileMode(m)&^os.ModePerm != 0 {
+			return nil, microerror.Maskf(invalidConfigError, "%T.Socket.Mode must be an octal file mode, got %q", config, config.Socket.Mode)
+		}
+		socketMode = os.FileMode(m)
+
+		// The listener serves the router microkit registers all endpoints to,
+		// like the mtls listener.
+		socketServer = &http.Server{
+			Handler: router,
+		}
+	}
+
+	var grpcServer *rpc.Server
+	if config.GRPC.Address != "" {
+		c := rpc.Config{
+			Audit:     config.Service.Audit,
+			Deferrer:  config.Service.Deferrer,
+			Logger:    config.Logger,
+			Readiness: config.Service.Readiness,
+
+			Address:        config.GRPC.Address,
+			Authenticators: authenticators,
+		}
+
+		grpcServer, err = rpc.New(c)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+	}
+
+	// The stream endpoint is not a microkit endpoint, because microkit buffers
+	// response bodies. It is served by the same router, so that it is
+	// available on all listeners.
+	{
+		e := endpointCollection.DeferrerStream
+		router.Methods(e.Method()).Path(e.Path()).Handler(e)
+	}
+
+	endpoints := []microserver.Endpoint{
+		endpointCollection.Deferrer,
+		endpointCollection.DeferrerOverrideDelete,
+		endpointCollection.DeferrerOverridePut,
+		endpointCollection.DeferrerWait,
+		endpointCollection.Healthz,
+		endpointCollection.Readyz,
+		endpointCollection.Version,
+	}
+	// Decisions for arbitrary PODs are only served in multi-tenant mode, so
+	// that requests for other PODs are answered with 404 otherwise.
+	if config.Service.Deferrer.MultiTenant() {
+		endpoints = append(endpoints, endpointCollection.DeferrerPod)
+	}
+
 	s := &Server{
-		logger:   config.Logger,
-		bootOnce: sync.Once{},
+		logger:  config.Logger,
+		service: config.Service,
 		config: microserver.Config{
 			Logger:      config.Logger,
+			Router:      router,
 			ServiceName: config.ProjectName,
 			Viper:       config.Viper,
 
-			Endpoints: []microserver.Endpoint{
-				endpointCollection.Deferrer,
-				endpointCollection.Healthz,
-				endpointCollection.Version,
-			},
+			Endpoints:    endpoints,
 			ErrorEncoder: errorEncoder,
+			RequestFuncs: []kithttp.RequestFunc{
+				auth.RequestFunc,
+			},
 		},
+		grpcServer:   grpcServer,
+		mtlsServer:   mtlsServer,
 		shutdownOnce: sync.Once{},
+		socketServer: socketServer,
+		startOnce:    sync.Once{},
+
+		drainPeriod: config.DrainPeriod,
+		socketMode:  socketMode,
+		socketPath:  config.Socket.Path,
 	}
 
 	return s, nil
 }
 
+// Boot implements microserver.Server. It starts the additional listeners like
+// Start, but only logs failures. The daemon command calls Start instead.
 func (s *Server) Boot() {
-	s.bootOnce.Do(func() {
-		// Insert here custom boot logic for server/endpoint/middleware if needed.
+	err := s.Start()
+	if err != nil {
+		_ = s.logger.LogCtx(context.Background(), "level", "error", "message", "failed to start server", "stack", fmt.Sprintf("%#v", err))
+	}
+}
+
+// Start starts the additional listeners serving the router of the microkit
+// server, i.e. the mtls and Unix domain socket listeners, and the gRPC server.
+// It must be called after the microkit server is booted, so that all endpoints
+// are registered to the router. An error is returned when any configured
+// listener cannot be started, so that misconfiguration fails the daemon.
+func (s *Server) Start() error {
+	s.startOnce.Do(func() {
+		s.startErr = s.start()
 	})
+
+	return s.startErr
+}
+
+func (s *Server) start() error {
+	ctx := context.Background()
+
+	if s.grpcServer != nil {
+		err := s.grpcServer.Boot()
+		if err != nil {
+			return microerror.Mask(err)
+		}
+	}
+
+	if s.mtlsServer != nil {
+		listener, err := net.Listen("tcp", s.mtlsServer.Addr)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+
+		go func() {
+			_ = s.logger.LogCtx(ctx, "level", "debug", "message", fmt.Sprintf("running mtls server at %s", s.mtlsServer.Addr))
+
+			err := s.mtlsServer.ServeTLS(listener, "", "")
+			if err != nil && err != http.ErrServerClosed {
+				_ = s.logger.LogCtx(ctx, "level", "error", "message", "failed to run mtls server", "stack", fmt.Sprintf("%#v", err))
+			}
+		}()
+	}
+
+	if s.socketServer != nil {
+		listener, err := listenUnix(s.socketPath, s.socketMode)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+
+		go func() {
+			_ = s.logger.LogCtx(ctx, "level", "debug", "message", fmt.Sprintf("running socket server at %s", s.socketPath))
+
+			err := s.socketServer.Serve(listener)
+			if err != nil && err != http.ErrServerClosed {
+				_ = s.logger.LogCtx(ctx, "level", "error", "message", "failed to run socket server", "stack", fmt.Sprintf("%#v", err))
+			}
+		}()
+	}
+
+	return nil
 }
 
 func (s *Server) Config() microserver.Config {
 	return s.config
 }
 
+// Shutdown blocks until the server is ready to stop serving. Other containers
+// of the POD may still query the defer endpoint after we have been asked to
+// shut down, so we keep serving until we have answered a decision allowing
+// node termination after we have been asked to shut down, or the drain period
+// passed.
 func (s *Server) Shutdown() {
 	s.shutdownOnce.Do(func() {
-		// Insert here custom shutdown logic for server/endpoint/middleware if needed.
+		ctx := context.Background()
+
+		if s.drainPeriod <= 0 {
+			return
+		}
+
+		// Decisions allowing node termination made before the shutdown began
+		// do not count, because other clients, e.g. the preStop hooks of
+		// other containers, may still be waiting for their answer.
+		allowed := s.service.Deferrer.Allowed()
+
+		_ = s.logger.LogCtx(ctx, "level", "debug", "message", fmt.Sprintf("waiting up to %s for the final decision allowing node termination", s.drainPeriod))
+
+		select {
+		case <-allowed:
+			_ = s.logger.LogCtx(ctx, "level", "debug", "message", "answered final decision allowing node termination")
+		case <-time.After(s.drainPeriod):
+			_ = s.logger.LogCtx(ctx, "level", "debug", "message", fmt.Sprintf("drain period of %s passed", s.drainPeriod))
+		}
 	})
+
+	s.shutdownListeners()
+}
+
+// shutdownListeners gracefully shuts down the additional listeners and the
+// gRPC server.
+func (s *Server) shutdownListeners() {
+	ctx := context.Background()
+
+	if s.grpcServer != nil {
+		s.grpcServer.Stop()
+	}
+
+	if s.mtlsServer != nil {
+		err := s.mtlsServer.Shutdown(ctx)
+		if err != nil {
+			_ = s.logger.LogCtx(ctx, "level", "error", "message", "failed to shut down mtls server", "stack", fmt.Sprintf("%#v", err))
+		}
+	}
+
+	if s.socketServer != nil {
+		err := s.socketServer.Shutdown(ctx)
+		if err != nil {
+			_ = s.logger.LogCtx(ctx, "level", "error", "message", "failed to shut down socket server", "stack", fmt.Sprintf("%#v", err))
+		}
+	}
+}
+
+// listenUnix listens on the Unix domain socket at the given path with the
+// given file mode. A socket left behind by a previous run, e.g. in a volume
+// shared with the node, is removed. Other files at the path are not.
+func listenUnix(path string, mode os.FileMode) (net.Listener, error) {
+	fi, err := os.Lstat(path)
+	if os.IsNotExist(err) {
+		// Nothing to clean up.
+	} else if err != nil {
+		return nil, microerror.Mask(err)
+	} else if fi.Mode()&os.ModeSocket == 0 {
+		return nil, microerror.Maskf(invalidConfigError, "%s exists and is not a socket", path)
+	} else {
+		err = os.Remove(path)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+	}
+
+	// The socket is created with the given mode right away. Changing the mode
+	// after listening would leave a window in which the socket is accessible
+	// according to the umask of the process. The umask is process wide, which
+	// is fine because listeners are only started during boot.
+	umask := syscall.Umask(int(os.ModePerm &^ mode))
+	listener, err := net.Listen("unix", path)
+	syscall.Umask(umask)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	return listener, nil
 }
 
 func errorEncoder(ctx context.Context, err error, w http.ResponseWriter) {
 	rErr := err.(microserver.ResponseError)
 	uErr := rErr.Underlying()
 
-	rErr.SetCode(microserver.CodeInternalError)
-	rErr.SetMessage(uErr.Error())
-	w.WriteHeader(http.StatusInternalServerError)
+	switch {
+	case auth.IsUnauthenticated(uErr):
+		rErr.SetCode(microserver.CodeInvalidCredentials)
+		rErr.SetMessage("authentication required")
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		w.WriteHeader(http.StatusUnauthorized)
+	case deferrer.IsInvalidRequest(uErr), override.IsInvalidRequest(uErr), wait.IsInvalidRequest(uErr), servicedeferrer.IsInvalidRequest(uErr):
+		rErr.SetCode(microserver.CodeInvalidInput)
+		rErr.SetMessage(uErr.Error())
+		w.WriteHeader(http.StatusBadRequest)
+	case servicedeferrer.IsNotFound(uErr):
+		rErr.SetCode(microserver.CodeResourceNotFound)
+		rErr.SetMessage(uErr.Error())
+		w.WriteHeader(http.StatusNotFound)
+	default:
+		rErr.SetCode(microserver.CodeInternalError)
+		rErr.SetMessage(uErr.Error())
+		w.WriteHeader(http.StatusInternalServerError)
+	}
 }
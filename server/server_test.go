@@ -0,0 +1,97 @@
+package server
+
+import (
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+)
+
+func Test_listenUnix(t *testing.T) {
+	testCases := []struct {
+		name         string
+		setup        func(path string) error
+		errorMatcher func(error) bool
+	}{
+		{
+			name:         "case 0: should listen on new socket",
+			setup:        func(path string) error { return nil },
+			errorMatcher: nil,
+		},
+		{
+			name: "case 1: should replace stale socket",
+			setup: func(path string) error {
+				l, err := net.Listen("unix", path)
+				if err != nil {
+					return err
+				}
+				// Keep the socket file when closing, like a crashed process.
+				l.(*net.UnixListener).SetUnlinkOnClose(false)
+				return l.Close()
+			},
+			errorMatcher: nil,
+		},
+		{
+			name: "case 2: should not replace regular file",
+			setup: func(path string) error {
+				return ioutil.WriteFile(path, []byte("foo"), 0600)
+			},
+			errorMatcher: IsInvalidConfig,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			dir, err := ioutil.TempDir("", "shutdown-deferrer")
+			if err != nil {
+				t.Fatalf("error == %#v, want nil", err)
+			}
+			defer os.RemoveAll(dir)
+
+			path := filepath.Join(dir, "deferrer.sock")
+
+			err = tc.setup(path)
+			if err != nil {
+				t.Fatalf("error == %#v, want nil", err)
+			}
+
+			umask := syscall.Umask(0022)
+			defer syscall.Umask(umask)
+
+			listener, err := listenUnix(path, 0660)
+
+			if restored := syscall.Umask(0022); restored != 0022 {
+				t.Fatalf("umask == %#o, want %#o", restored, 0022)
+			}
+
+			switch {
+			case err == nil && tc.errorMatcher == nil:
+				// correct; carry on
+			case err != nil && tc.errorMatcher == nil:
+				t.Fatalf("error == %#v, want nil", err)
+			case err == nil && tc.errorMatcher != nil:
+				t.Fatalf("error == nil, want non-nil")
+			case !tc.errorMatcher(err):
+				t.Fatalf("error == %#v, want matching", err)
+			}
+
+			if tc.errorMatcher != nil {
+				return
+			}
+			defer listener.Close()
+
+			fi, err := os.Stat(path)
+			if err != nil {
+				t.Fatalf("error == %#v, want nil", err)
+			}
+			if fi.Mode()&os.ModeSocket == 0 {
+				t.Fatalf("mode == %s, want socket", fi.Mode())
+			}
+			if fi.Mode().Perm() != 0660 {
+				t.Fatalf("permissions == %#o, want %#o", fi.Mode().Perm(), 0660)
+			}
+		})
+	}
+}
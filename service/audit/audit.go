@@ -0,0 +1,247 @@
+// Package audit implements the audit trail of defer queries. Every query is
+// written as JSON line to a file or stdout, so that it is possible to
+// reconstruct when a caller was told that node termination is allowed.
+package audit
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/giantswarm/microerror"
+
+	"github.com/giantswarm/shutdown-deferrer/service/deferrer"
+)
+
+const (
+	// PathStdout is the path writing the audit trail to stdout.
+	PathStdout = "-"
+)
+
+// Config represents the configuration used to create a new audit logger.
+type Config struct {
+	// Path is the file the audit trail is written to. PathStdout writes it to
+	// stdout. The audit trail is disabled when Path is empty.
+	Path string
+	// MaxBackups is the number of rotated files kept next to Path.
+	MaxBackups int
+	// MaxSize is the size in bytes after which the file at Path is rotated.
+	// Zero disables rotation.
+	MaxSize int64
+}
+
+// Caller identifies the client of a defer query.
+type Caller struct {
+	RemoteAddr string
+	UserAgent  string
+}
+
+// NewCaller returns the caller of the given HTTP request.
+func NewCaller(r *http.Request) Caller {
+	return Caller{
+		RemoteAddr: r.RemoteAddr,
+		UserAgent:  r.UserAgent(),
+	}
+}
+
+// Entry is a single line of the audit trail.
+type Entry struct {
+	Time     time.Time `json:"time"`
+	Endpoint string    `json:"endpoint"`
+
+	RemoteAddr string `json:"remoteAddr"`
+	UserAgent  string `json:"userAgent"`
+
+	// Pod is the namespace and name of the POD decided for in multi-tenant
+	// mode, if any.
+	Pod string `json:"pod,omitempty"`
+
+	Defer    bool   `json:"defer"`
+	Reason   string `json:"reason,omitempty"`
+	Provider string `json:"provider,omitempty"`
+
+	DrainerConfig *EntryDrainerConfig `json:"drainerConfig,omitempty"`
+
+	// Error is the message of the error failing the query or the provider
+	// lookup, if any.
+	Error string `json:"error,omitempty"`
+
+	LatencySeconds float64 `json:"latencySeconds"`
+}
+
+type EntryDrainerConfig struct {
+	Name            string `json:"name"`
+	Namespace       string `json:"namespace"`
+	ResourceVersion string `json:"resourceVersion"`
+}
+
+// NewEntry returns the entry of a defer query of the given caller to the
+// given endpoint, which started at the given time and resulted in the given
+// decision or error.
+func NewEntry(endpoint string, caller Caller, start time.Time, decision deferrer.Decision, err error) Entry {
+	e := Entry{
+		Time:     start.UTC(),
+		Endpoint: endpoint,
+
+		RemoteAddr: caller.RemoteAddr,
+		UserAgent:  caller.UserAgent,
+
+		Defer:    decision.Defer,
+		Reason:   decision.Reason,
+		Provider: decision.Provider,
+
+		Error: decision.Error,
+
+		LatencySeconds: time.Since(start).Seconds(),
+	}
+
+	if decision.DrainerConfig != nil {
+		e.DrainerConfig = &EntryDrainerConfig{
+			Name:            decision.DrainerConfig.Name,
+			Namespace:       decision.DrainerConfig.Namespace,
+			ResourceVersion: decision.DrainerConfig.ResourceVersion,
+		}
+	}
+
+	if err != nil {
+		e.Error = err.Error()
+	}
+
+	return e
+}
+
+// Logger writes entries to the audit trail. It is safe for concurrent use.
+type Logger struct {
+	path       string
+	maxBackups int
+	maxSize    int64
+
+	file   *os.File
+	mutex  sync.Mutex
+	size   int64
+	writer io.Writer
+}
+
+// New creates a new audit logger. The file at the configured path is opened
+// for appending right away, so that misconfiguration fails at startup.
+func New(config Config) (*Logger, error) {
+	if config.MaxBackups < 0 {
+		return nil, microerror.Maskf(invalidConfigError, "%T.MaxBackups must not be negative", config)
+	}
+	if config.MaxSize < 0 {
+		return nil, microerror.Maskf(invalidConfigError, "%T.MaxSize must not be negative", config)
+	}
+
+	l := &Logger{
+		path:       config.Path,
+		maxBackups: config.MaxBackups,
+		maxSize:    config.MaxSize,
+	}
+
+	switch config.Path {
+	case "":
+	case PathStdout:
+		l.writer = os.Stdout
+	default:
+		err := l.open()
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+	}
+
+	return l, nil
+}
+
+// Log writes the given entry as JSON line. Entries are discarded when the
+// audit trail is disabled.
+func (l *Logger) Log(entry Entry) error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if l.writer == nil {
+		return nil
+	}
+
+	b, err := json.Marshal(entry)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+	b = append(b, '\n')
+
+	if l.file != nil && l.maxSize > 0 && l.size > 0 && l.size+int64(len(b)) > l.maxSize {
+		err = l.rotate()
+		if err != nil {
+			return microerror.Mask(err)
+		}
+	}
+
+	n, err := l.writer.Write(b)
+	l.size += int64(n)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	return nil
+}
+
+func (l *Logger) open() error {
+	f, err := os.OpenFile(l.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0640)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		_ = f.Close()
+		return microerror.Mask(err)
+	}
+
+	l.file = f
+	l.size = info.Size()
+	l.writer = f
+
+	return nil
+}
+
+// rotate renames the current file to <path>.1, shifting existing backups up
+// to MaxBackups, and opens a new file.
+func (l *Logger) rotate() error {
+	err := l.file.Close()
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	if l.maxBackups == 0 {
+		err = os.Remove(l.path)
+		if err != nil && !os.IsNotExist(err) {
+			return microerror.Mask(err)
+		}
+	} else {
+		for i := l.maxBackups - 1; i > 0; i-- {
+			err = os.Rename(backupPath(l.path, i), backupPath(l.path, i+1))
+			if err != nil && !os.IsNotExist(err) {
+				return microerror.Mask(err)
+			}
+		}
+
+		err = os.Rename(l.path, backupPath(l.path, 1))
+		if err != nil && !os.IsNotExist(err) {
+			return microerror.Mask(err)
+		}
+	}
+
+	err = l.open()
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	return nil
+}
+
+func backupPath(path string, i int) string {
+	return fmt.Sprintf("%s.%d", path, i)
+}
@@ -0,0 +1,102 @@
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/giantswarm/microerror"
+
+	"github.com/giantswarm/shutdown-deferrer/service/deferrer"
+)
+
+func Test_Logger_Log(t *testing.T) {
+	dir, err := ioutil.TempDir("", "audit")
+	if err != nil {
+		t.Fatalf("error == %#v, want nil", err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "audit.log")
+
+	l, err := New(Config{
+		Path:       path,
+		MaxBackups: 1,
+		MaxSize:    1,
+	})
+	if err != nil {
+		t.Fatalf("error == %#v, want nil", err)
+	}
+
+	caller := Caller{RemoteAddr: "10.0.0.1:1234", UserAgent: "qemu-shutdown"}
+	decision := deferrer.Decision{
+		Defer:    false,
+		Reason:   deferrer.ReasonDrained,
+		Provider: deferrer.ProviderDrainerConfig,
+		DrainerConfig: &deferrer.DecisionDrainerConfig{
+			Name:            "foo",
+			Namespace:       "bar",
+			ResourceVersion: "42",
+		},
+	}
+
+	// Every entry exceeds the maximum size, so each one after the first
+	// rotates the file and only the last two entries are kept.
+	entries := []Entry{
+		NewEntry("deferrer", caller, time.Now(), deferrer.Decision{Defer: true}, nil),
+		NewEntry("deferrer", caller, time.Now(), deferrer.Decision{}, microerror.New("test")),
+		NewEntry("deferrer", caller, time.Now(), decision, nil),
+	}
+	for _, e := range entries {
+		err = l.Log(e)
+		if err != nil {
+			t.Fatalf("error == %#v, want nil", err)
+		}
+	}
+
+	backup := readEntries(t, path+".1")
+	if len(backup) != 1 || backup[0].Error != "test" {
+		t.Fatalf("backup == %#v, want entry with error", backup)
+	}
+
+	current := readEntries(t, path)
+	if len(current) != 1 {
+		t.Fatalf("len(current) == %d, want 1", len(current))
+	}
+	if current[0].RemoteAddr != caller.RemoteAddr || current[0].UserAgent != caller.UserAgent {
+		t.Fatalf("caller == %s %s, want %s %s", current[0].RemoteAddr, current[0].UserAgent, caller.RemoteAddr, caller.UserAgent)
+	}
+	if current[0].Reason != deferrer.ReasonDrained || current[0].DrainerConfig == nil || current[0].DrainerConfig.ResourceVersion != "42" {
+		t.Fatalf("entry == %#v, want Drained decision of DrainerConfig with resourceVersion 42", current[0])
+	}
+
+	_, err = os.Stat(path + ".2")
+	if !os.IsNotExist(err) {
+		t.Fatalf("error == %#v, want not exist", err)
+	}
+}
+
+func readEntries(t *testing.T, path string) []Entry {
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("error == %#v, want nil", err)
+	}
+	defer f.Close()
+
+	var entries []Entry
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var e Entry
+		err := json.Unmarshal(scanner.Bytes(), &e)
+		if err != nil {
+			t.Fatalf("error == %#v, want nil", err)
+		}
+		entries = append(entries, e)
+	}
+
+	return entries
+}
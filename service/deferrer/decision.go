@@ -0,0 +1,105 @@
+package deferrer
+
+import (
+	"time"
+)
+
+const (
+	// ReasonAPIError is the reason used when a provider failed to look up its
+	// signal, e.g. the DrainerConfig. The decision is made according to the
+	// failure policy.
+	ReasonAPIError = "APIError"
+	// ReasonDeadlineExceeded is the reason used when node termination has been
+	// deferred for longer than the configured deadline.
+	ReasonDeadlineExceeded = "DeadlineExceeded"
+	// ReasonDisruptionAllowed is the reason used when the PodDisruptionBudgets
+	// allow evicting all pods remaining on the node of the POD.
+	ReasonDisruptionAllowed = "DisruptionAllowed"
+	// ReasonDisruptionNotAllowed is the reason used when a PodDisruptionBudget
+	// does not allow evicting a pod remaining on the node of the POD.
+	ReasonDisruptionNotAllowed = "DisruptionNotAllowed"
+	// ReasonDrained is the reason used when the DrainerConfig has a Drained
+	// condition.
+	ReasonDrained = "Drained"
+	// ReasonForced is the reason used when node termination is forced to be
+	// allowed or deferred by the AnnotationForce annotation.
+	ReasonForced = "Forced"
+	// ReasonNodeDrained is the reason used when the node of the POD is
+	// cordoned and no evictable pods remain on it.
+	ReasonNodeDrained = "NodeDrained"
+	// ReasonNodeNotCordoned is the reason used when the node of the POD is not
+	// cordoned.
+	ReasonNodeNotCordoned = "NodeNotCordoned"
+	// ReasonNodeNotFound is the reason used when the node of the POD does not
+	// exist.
+	ReasonNodeNotFound = "NodeNotFound"
+	// ReasonNoConditions is the reason used when the DrainerConfig has neither
+	// a Drained nor a Timeout condition.
+	ReasonNoConditions = "NoConditions"
+	// ReasonNoDrainerConfig is the reason used when the DrainerConfig does not
+	// exist.
+	ReasonNoDrainerConfig = "NoDrainerConfig"
+	// ReasonOverride is the reason used when node termination is forced to be
+	// allowed or deferred by an Override.
+	ReasonOverride = "Override"
+	// ReasonPodsRemaining is the reason used when evictable pods remain on
+	// the cordoned node of the POD.
+	ReasonPodsRemaining = "PodsRemaining"
+	// ReasonTimeout is the reason used when the DrainerConfig has a Timeout
+	// condition.
+	ReasonTimeout = "Timeout"
+)
+
+// Decision describes whether node termination has to be deferred and why.
+type Decision struct {
+	// Defer is true when node termination has to be deferred.
+	Defer bool
+	// Reason is one of the Reason* constants explaining the decision.
+	Reason string
+	// Provider is the name of the provider which made the decision.
+	Provider string
+
+	// DrainerConfig is the DrainerConfig the decision is based on, if any.
+	DrainerConfig *DecisionDrainerConfig
+	// Condition is the DrainerConfig condition the decision is based on, if
+	// any.
+	Condition *DecisionCondition
+	// Error is the message of the error which occurred while looking up the
+	// signal of the provider, if any.
+	Error string
+	// MaxDefer overrides the configured deadline after which node termination
+	// is not deferred anymore, if not zero.
+	MaxDefer time.Duration
+	// Override is the override the decision is based on, if any.
+	Override *Override
+	// Force is one of ForceAllow or ForceDefer when the provider found the
+	// decision to be forced, e.g. by the AnnotationForce annotation of the
+	// DrainerConfig. It takes precedence over the decisions of other
+	// providers.
+	Force string
+}
+
+// newForcedDecision returns the Decision of the given provider forced by the
+// AnnotationForce annotation.
+func newForcedDecision(force string, provider string) Decision {
+	return Decision{
+		Defer:    force == ForceDefer,
+		Reason:   ReasonForced,
+		Provider: provider,
+		Force:    force,
+	}
+}
+
+// DecisionDrainerConfig identifies the DrainerConfig a Decision is based on.
+type DecisionDrainerConfig struct {
+	Name            string
+	Namespace       string
+	ResourceVersion string
+}
+
+// DecisionCondition is the DrainerConfig condition a Decision is based on.
+type DecisionCondition struct {
+	LastTransitionTime time.Time
+	Status             string
+	Type               string
+}
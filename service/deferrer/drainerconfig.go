@@ -0,0 +1,567 @@
+package deferrer
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/giantswarm/apiextensions/pkg/apis/core/v1alpha1"
+	"github.com/giantswarm/apiextensions/pkg/clientset/versioned"
+	"github.com/giantswarm/microerror"
+	"github.com/giantswarm/micrologger"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metasv1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/tools/cache"
+)
+
+const (
+	// ProviderDrainerConfig is the name of the DrainerConfigProvider.
+	ProviderDrainerConfig = "drainerconfig"
+)
+
+const (
+	// resyncPeriod is the interval in which the DrainerConfig informer
+	// refreshes its cache from the API server, additionally to the updates it
+	// receives through its watch.
+	resyncPeriod = 1 * time.Minute
+)
+
+type DrainerConfigProviderConfig struct {
+	G8sClient versioned.Interface
+	Logger    micrologger.Logger
+
+	// Target overrides the DrainerConfig looked up for the POD. By default the
+	// DrainerConfig named like the POD in the POD's namespace is used.
+	Target DrainerConfigTarget
+}
+
+// DrainerConfigTarget defines which DrainerConfig is looked up. Empty fields
+// default to the name and namespace of the POD.
+type DrainerConfigTarget struct {
+	Name      string
+	Namespace string
+	// LabelSelector selects the DrainerConfig by labels instead of by name. It
+	// must match at most one DrainerConfig in the namespace. Name and
+	// LabelSelector are mutually exclusive.
+	LabelSelector string
+}
+
+type drainerConfigTargetKey struct{}
+
+// NewDrainerConfigTargetContext returns a context carrying the given target,
+// which overrides the configured target of the DrainerConfigProvider for
+// decisions made with this context. Empty fields of the given target do not
+// override the configured ones.
+func NewDrainerConfigTargetContext(ctx context.Context, target DrainerConfigTarget) context.Context {
+	return context.WithValue(ctx, drainerConfigTargetKey{}, target)
+}
+
+// DrainerConfigTargetFromContext returns the target stored in the given
+// context, if any.
+func DrainerConfigTargetFromContext(ctx context.Context) (DrainerConfigTarget, bool) {
+	target, ok := ctx.Value(drainerConfigTargetKey{}).(DrainerConfigTarget)
+	return target, ok
+}
+
+// Validate returns an invalidConfigError when the given target cannot be used
+// to look up a DrainerConfig.
+func (t DrainerConfigTarget) Validate() error {
+	if t.Name != "" && t.LabelSelector != "" {
+		return microerror.Maskf(invalidConfigError, "%T.Name and %T.LabelSelector must not both be given", t, t)
+	}
+	if t.LabelSelector != "" {
+		_, err := labels.Parse(t.LabelSelector)
+		if err != nil {
+			return microerror.Maskf(invalidConfigError, "%T.LabelSelector %q is invalid: %s", t, t.LabelSelector, err.Error())
+		}
+	}
+
+	return nil
+}
+
+// merge returns t with its empty fields set from the given target. A name or
+// label selector given in t replaces both of them.
+func (t DrainerConfigTarget) merge(target DrainerConfigTarget) DrainerConfigTarget {
+	if t.Name == "" && t.LabelSelector == "" {
+		t.Name = target.Name
+		t.LabelSelector = target.LabelSelector
+	}
+	if t.Namespace == "" {
+		t.Namespace = target.Namespace
+	}
+
+	return t
+}
+
+// DrainerConfigProvider defers node termination until the DrainerConfig of
+// the POD has a Drained or Timeout condition.
+type DrainerConfigProvider struct {
+	g8sClient versioned.Interface
+	logger    micrologger.Logger
+	target    DrainerConfigTarget
+
+	changed        chan struct{}
+	informer       cache.SharedIndexInformer
+	informerAll    bool
+	informerTarget DrainerConfigTarget
+	lookupErr      error
+	mutex          sync.RWMutex
+}
+
+func NewDrainerConfigProvider(config DrainerConfigProviderConfig) (*DrainerConfigProvider, error) {
+	if config.G8sClient == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.G8sClient must not be empty", config)
+	}
+	if config.Logger == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Logger must not be empty", config)
+	}
+	err := config.Target.Validate()
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	p := &DrainerConfigProvider{
+		g8sClient: config.G8sClient,
+		logger:    config.Logger,
+		target:    config.Target,
+
+		changed: make(chan struct{}),
+	}
+
+	return p, nil
+}
+
+// Boot starts an informer watching the DrainerConfig of the given POD. The
+// informer is scoped to the DrainerConfig's namespace and name, or label
+// selector, so that the API server only has to send us events for the target
+// object. When the given POD is empty, as in multi-tenant mode, a single
+// informer shared by all PODs watches DrainerConfigs across namespaces. The
+// informer is stopped once the given context is done.
+func (p *DrainerConfigProvider) Boot(ctx context.Context, pod Pod) error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if p.informer != nil {
+		return nil
+	}
+
+	all := pod == Pod{}
+	target := p.resolveTarget(context.Background(), pod)
+	if all {
+		target = DrainerConfigTarget{Namespace: metasv1.NamespaceAll}
+	}
+
+	setSelectors := func(options *metasv1.ListOptions) {
+		if all {
+			return
+		}
+		if target.LabelSelector != "" {
+			options.LabelSelector = target.LabelSelector
+		} else {
+			options.FieldSelector = fields.OneTermEqualSelector("metadata.name", target.Name).String()
+		}
+	}
+
+	lw := &cache.ListWatch{
+		ListFunc: func(options metasv1.ListOptions) (runtime.Object, error) {
+			setSelectors(&options)
+			return p.g8sClient.CoreV1alpha1().DrainerConfigs(target.Namespace).List(options)
+		},
+		WatchFunc: func(options metasv1.ListOptions) (watch.Interface, error) {
+			setSelectors(&options)
+			return p.g8sClient.CoreV1alpha1().DrainerConfigs(target.Namespace).Watch(options)
+		},
+	}
+
+	p.informer = cache.NewSharedIndexInformer(lw, &v1alpha1.DrainerConfig{}, resyncPeriod, cache.Indexers{})
+	p.informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    func(obj interface{}) { p.notify() },
+		UpdateFunc: func(oldObj, newObj interface{}) { p.notify() },
+		DeleteFunc: func(obj interface{}) { p.notify() },
+	})
+	p.informerAll = all
+	p.informerTarget = target
+
+	go p.informer.Run(ctx.Done())
+
+	// Lookups are served from the cache once it is synced, so failures of
+	// lookups made before do not matter anymore.
+	informer := p.informer
+	go func() {
+		if cache.WaitForCacheSync(ctx.Done(), informer.HasSynced) {
+			p.setLookupErr(nil)
+		}
+	}()
+
+	return nil
+}
+
+// Changes returns a channel which is closed on the next DrainerConfig event
+// observed by the informer.
+func (p *DrainerConfigProvider) Changes() <-chan struct{} {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+
+	return p.changed
+}
+
+// Decide finds the DrainerConfig of the given POD and checks if the node of the
+// POD is drained yet. If the DrainerConfig doesn't exist or doesn't have a
+// Drained or Timeout condition, node termination should be deferred.
+//
+// The DrainerConfig looked up can be changed with the configured target and
+// per decision using NewDrainerConfigTargetContext.
+//
+// Once Boot has been called and the informer cache is synced, the DrainerConfig
+// is read from the cache. Until then, or when the target is overridden, it is
+// fetched from the API server directly.
+func (p *DrainerConfigProvider) Decide(ctx context.Context, pod Pod) (Decision, error) {
+	var drainerConfig *v1alpha1.DrainerConfig
+	{
+		_ = p.logger.LogCtx(ctx, "level", "debug", "message", "finding drainerconfig for pod")
+
+		target := p.resolveTarget(ctx, pod)
+		err := target.Validate()
+		if err != nil {
+			return Decision{}, microerror.Mask(err)
+		}
+
+		drainerConfig, err = p.getDrainerConfig(ctx, target)
+		if err != nil {
+			return Decision{}, microerror.Mask(err)
+		}
+
+		if drainerConfig == nil {
+			_ = p.logger.LogCtx(ctx, "level", "debug", "message", "did not find drainerconfig")
+		} else {
+			_ = p.logger.LogCtx(ctx, "level", "debug", "message", "found drainerconfig for pod")
+		}
+	}
+
+	{
+		_ = p.logger.LogCtx(ctx, "level", "debug", "message", "finding if node termination has to be defered")
+
+		if drainerConfig == nil {
+			_ = p.logger.LogCtx(ctx, "level", "debug", "message", "found node termination should be deferred")
+			_ = p.logger.LogCtx(ctx, "level", "debug", "message", "pod drainerconfig does not exist")
+
+			d := Decision{
+				Defer:  true,
+				Reason: ReasonNoDrainerConfig,
+			}
+
+			return d, nil
+		}
+
+		var d Decision
+		if drainerConfig.Status.HasDrainedCondition() {
+			_ = p.logger.LogCtx(ctx, "level", "debug", "message", "found node termination does not have to be deferred")
+			_ = p.logger.LogCtx(ctx, "level", "debug", "message", "pod drainerconfig has drained condition")
+
+			d = newDrainerConfigDecision(drainerConfig, false, ReasonDrained, v1alpha1.DrainerConfigStatusTypeDrained)
+		} else if drainerConfig.Status.HasTimeoutCondition() {
+			_ = p.logger.LogCtx(ctx, "level", "debug", "message", "found node termination does not have to be deferred")
+			_ = p.logger.LogCtx(ctx, "level", "debug", "message", "pod drainerconfig has timeout condition")
+
+			d = newDrainerConfigDecision(drainerConfig, false, ReasonTimeout, v1alpha1.DrainerConfigStatusTypeTimeout)
+		} else {
+			_ = p.logger.LogCtx(ctx, "level", "debug", "message", "found node termination should be deferred")
+
+			d = newDrainerConfigDecision(drainerConfig, true, ReasonNoConditions, "")
+		}
+
+		if v, ok := drainerConfig.GetAnnotations()[AnnotationMaxDefer]; ok {
+			maxDefer, err := parseMaxDefer(v)
+			if err != nil {
+				_ = p.logger.LogCtx(ctx, "level", "warning", "message", fmt.Sprintf("ignoring invalid drainerconfig annotation: %s", err))
+			} else {
+				d.MaxDefer = maxDefer
+			}
+		}
+
+		if v, ok := drainerConfig.GetAnnotations()[AnnotationForce]; ok {
+			force, err := parseForce(v)
+			if err != nil {
+				_ = p.logger.LogCtx(ctx, "level", "warning", "message", fmt.Sprintf("ignoring invalid drainerconfig annotation: %s", err))
+			} else {
+				_ = p.logger.LogCtx(ctx, "level", "debug", "message", fmt.Sprintf("found drainerconfig annotation forcing node termination to %s", force))
+				d.Force = force
+			}
+		}
+
+		return d, nil
+	}
+}
+
+func (p *DrainerConfigProvider) Name() string {
+	return ProviderDrainerConfig
+}
+
+// Permissions returns the permissions required to look up and watch the
+// DrainerConfig of the given POD, or all DrainerConfigs when the POD is empty.
+func (p *DrainerConfigProvider) Permissions(pod Pod) []Permission {
+	namespace := metasv1.NamespaceAll
+	if pod != (Pod{}) {
+		namespace = p.resolveTarget(context.Background(), pod).Namespace
+	}
+
+	return newPermissions(v1alpha1.SchemeGroupVersion.Group, "drainerconfigs", namespace, "get", "list", "watch")
+}
+
+// Ready returns a notReadyError until the informer cache is synced and when
+// the last DrainerConfig lookup of the configured target failed. Lookups of
+// targets given in the context of a query do not affect readiness.
+func (p *DrainerConfigProvider) Ready(ctx context.Context) error {
+	p.mutex.RLock()
+	informer := p.informer
+	lookupErr := p.lookupErr
+	p.mutex.RUnlock()
+
+	if informer == nil {
+		return microerror.Maskf(notReadyError, "drainerconfig informer not booted")
+	}
+	if !informer.HasSynced() {
+		return microerror.Maskf(notReadyError, "drainerconfig informer cache not synced")
+	}
+	if lookupErr != nil {
+		return microerror.Maskf(notReadyError, "last drainerconfig lookup failed: %s", lookupErr)
+	}
+
+	return nil
+}
+
+// notify wakes up all goroutines waiting for DrainerConfig changes.
+func (p *DrainerConfigProvider) notify() {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	close(p.changed)
+	p.changed = make(chan struct{})
+}
+
+// resolveTarget returns the target of the DrainerConfig lookup for the given
+// POD. A target given in the context takes precedence over the configured
+// target, which takes precedence over the POD's name and namespace.
+func (p *DrainerConfigProvider) resolveTarget(ctx context.Context, pod Pod) DrainerConfigTarget {
+	target := p.target.merge(DrainerConfigTarget{
+		Name:      pod.Name,
+		Namespace: pod.Namespace,
+	})
+
+	override, ok := DrainerConfigTargetFromContext(ctx)
+	if ok {
+		target = override.merge(target)
+	}
+
+	return target
+}
+
+// getDrainerConfig returns the DrainerConfig matching the given target. The
+// informer cache is used when it is synced and watches the given target or all
+// DrainerConfigs.
+// Otherwise the DrainerConfig is fetched from the API server. In case the
+// DrainerConfig does not exist nil is returned.
+func (p *DrainerConfigProvider) getDrainerConfig(ctx context.Context, target DrainerConfigTarget) (*v1alpha1.DrainerConfig, error) {
+	p.mutex.RLock()
+	informer := p.informer
+	informerAll := p.informerAll
+	informerTarget := p.informerTarget
+	p.mutex.RUnlock()
+
+	_, callerTarget := DrainerConfigTargetFromContext(ctx)
+
+	if informer != nil && informer.HasSynced() && (informerAll || informerTarget == target) {
+		_ = p.logger.LogCtx(ctx, "level", "debug", "message", "using informer cache to find drainerconfig")
+
+		drainerConfig, err := p.getDrainerConfigFromCache(informer, target)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+
+		if !callerTarget {
+			p.setLookupErr(nil)
+		}
+
+		return drainerConfig, nil
+	}
+
+	_ = p.logger.LogCtx(ctx, "level", "debug", "message", "informer cache not synced yet or not watching target, requesting drainerconfig from api server")
+
+	drainerConfig, err := p.getDrainerConfigFromAPIServer(target)
+
+	if !callerTarget {
+		p.setLookupErr(err)
+	}
+
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	return drainerConfig, nil
+}
+
+// setLookupErr remembers the result of the last DrainerConfig lookup of the
+// configured target for Ready.
+func (p *DrainerConfigProvider) setLookupErr(err error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	p.lookupErr = err
+}
+
+func (p *DrainerConfigProvider) getDrainerConfigFromAPIServer(target DrainerConfigTarget) (*v1alpha1.DrainerConfig, error) {
+	defer func(t time.Time) {
+		lookupHistogram.WithLabelValues(lookupSourceAPIServer).Observe(time.Since(t).Seconds())
+	}(time.Now())
+
+	if target.LabelSelector != "" {
+		list, err := p.g8sClient.CoreV1alpha1().DrainerConfigs(target.Namespace).List(metasv1.ListOptions{LabelSelector: target.LabelSelector})
+		if err != nil {
+			lookupErrorCounter.WithLabelValues(lookupSourceAPIServer, errorType(err)).Inc()
+			return nil, microerror.Mask(err)
+		}
+
+		var drainerConfigs []*v1alpha1.DrainerConfig
+		for i := range list.Items {
+			drainerConfigs = append(drainerConfigs, &list.Items[i])
+		}
+
+		drainerConfig, err := selectDrainerConfig(target, drainerConfigs)
+		if err != nil {
+			lookupErrorCounter.WithLabelValues(lookupSourceAPIServer, tooManyDrainerConfigsError.Kind).Inc()
+			return nil, microerror.Mask(err)
+		}
+
+		return drainerConfig, nil
+	}
+
+	drainerConfig, err := p.g8sClient.CoreV1alpha1().DrainerConfigs(target.Namespace).Get(target.Name, metasv1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil, nil
+	} else if err != nil {
+		lookupErrorCounter.WithLabelValues(lookupSourceAPIServer, errorType(err)).Inc()
+		return nil, microerror.Mask(err)
+	}
+
+	return drainerConfig, nil
+}
+
+func (p *DrainerConfigProvider) getDrainerConfigFromCache(informer cache.SharedIndexInformer, target DrainerConfigTarget) (*v1alpha1.DrainerConfig, error) {
+	defer func(t time.Time) {
+		lookupHistogram.WithLabelValues(lookupSourceCache).Observe(time.Since(t).Seconds())
+	}(time.Now())
+
+	if target.LabelSelector != "" {
+		var drainerConfigs []*v1alpha1.DrainerConfig
+		for _, obj := range informer.GetStore().List() {
+			drainerConfig, ok := obj.(*v1alpha1.DrainerConfig)
+			if !ok {
+				lookupErrorCounter.WithLabelValues(lookupSourceCache, wrongTypeError.Kind).Inc()
+				return nil, microerror.Maskf(wrongTypeError, "expected %T, got %T", drainerConfig, obj)
+			}
+
+			drainerConfigs = append(drainerConfigs, drainerConfig)
+		}
+
+		drainerConfig, err := selectDrainerConfig(target, drainerConfigs)
+		if err != nil {
+			lookupErrorCounter.WithLabelValues(lookupSourceCache, tooManyDrainerConfigsError.Kind).Inc()
+			return nil, microerror.Mask(err)
+		}
+		if drainerConfig == nil {
+			return nil, nil
+		}
+
+		return drainerConfig.DeepCopy(), nil
+	}
+
+	obj, exists, err := informer.GetStore().GetByKey(fmt.Sprintf("%s/%s", target.Namespace, target.Name))
+	if err != nil {
+		lookupErrorCounter.WithLabelValues(lookupSourceCache, errorType(err)).Inc()
+		return nil, microerror.Mask(err)
+	}
+	if !exists {
+		return nil, nil
+	}
+
+	drainerConfig, ok := obj.(*v1alpha1.DrainerConfig)
+	if !ok {
+		lookupErrorCounter.WithLabelValues(lookupSourceCache, wrongTypeError.Kind).Inc()
+		return nil, microerror.Maskf(wrongTypeError, "expected %T, got %T", drainerConfig, obj)
+	}
+
+	return drainerConfig.DeepCopy(), nil
+}
+
+// selectDrainerConfig returns the single DrainerConfig of the given ones which
+// matches the label selector of the given target. In case none matches nil is
+// returned.
+func selectDrainerConfig(target DrainerConfigTarget, drainerConfigs []*v1alpha1.DrainerConfig) (*v1alpha1.DrainerConfig, error) {
+	selector, err := labels.Parse(target.LabelSelector)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	var matching []*v1alpha1.DrainerConfig
+	for _, dc := range drainerConfigs {
+		if dc.GetNamespace() == target.Namespace && selector.Matches(labels.Set(dc.GetLabels())) {
+			matching = append(matching, dc)
+		}
+	}
+
+	if len(matching) > 1 {
+		return nil, microerror.Maskf(tooManyDrainerConfigsError, "found %d drainerconfigs matching label selector %q in namespace %s", len(matching), target.LabelSelector, target.Namespace)
+	}
+	if len(matching) == 0 {
+		return nil, nil
+	}
+
+	return matching[0], nil
+}
+
+// newDrainerConfigDecision creates a Decision based on the given DrainerConfig.
+// When conditionType is not empty, the matching condition of the DrainerConfig
+// is added to the Decision.
+func newDrainerConfigDecision(drainerConfig *v1alpha1.DrainerConfig, shouldDefer bool, reason string, conditionType string) Decision {
+	d := Decision{
+		Defer:  shouldDefer,
+		Reason: reason,
+
+		DrainerConfig: &DecisionDrainerConfig{
+			Name:            drainerConfig.GetName(),
+			Namespace:       drainerConfig.GetNamespace(),
+			ResourceVersion: drainerConfig.GetResourceVersion(),
+		},
+	}
+
+	if conditionType != "" {
+		for _, c := range drainerConfig.Status.Conditions {
+			if c.Status == v1alpha1.DrainerConfigStatusStatusTrue && c.Type == conditionType {
+				d.Condition = &DecisionCondition{
+					LastTransitionTime: c.LastTransitionTime.Time,
+					Status:             c.Status,
+					Type:               c.Type,
+				}
+				break
+			}
+		}
+	}
+
+	return d
+}
+
+// errorType returns the Kubernetes API status reason of the given error, which
+// is used to label lookup error metrics.
+func errorType(err error) string {
+	reason := apierrors.ReasonForError(err)
+	if reason == metasv1.StatusReasonUnknown {
+		return "Unknown"
+	}
+
+	return string(reason)
+}
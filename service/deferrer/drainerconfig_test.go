@@ -0,0 +1,237 @@
+package deferrer
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/giantswarm/apiextensions/pkg/apis/core/v1alpha1"
+	"github.com/giantswarm/apiextensions/pkg/clientset/versioned/fake"
+	"github.com/giantswarm/micrologger/microloggertest"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func Test_DrainerConfigProvider_Target(t *testing.T) {
+	newDrainerConfig := func(name, namespace string, labels map[string]string) *v1alpha1.DrainerConfig {
+		return &v1alpha1.DrainerConfig{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: namespace,
+				Labels:    labels,
+			},
+		}
+	}
+
+	testCases := []struct {
+		name                  string
+		target                DrainerConfigTarget
+		contextTarget         *DrainerConfigTarget
+		drainerConfigs        []runtime.Object
+		expectedDrainerConfig *DecisionDrainerConfig
+		errorMatcher          func(error) bool
+	}{
+		{
+			name: "case 0: look up drainerconfig named like the pod by default",
+			drainerConfigs: []runtime.Object{
+				newDrainerConfig("foo", "bar", nil),
+				newDrainerConfig("node0", "bar", nil),
+			},
+			expectedDrainerConfig: &DecisionDrainerConfig{Name: "foo", Namespace: "bar"},
+		},
+		{
+			name:   "case 1: look up drainerconfig with configured name and namespace",
+			target: DrainerConfigTarget{Name: "node0", Namespace: "kube-system"},
+			drainerConfigs: []runtime.Object{
+				newDrainerConfig("foo", "bar", nil),
+				newDrainerConfig("node0", "kube-system", nil),
+			},
+			expectedDrainerConfig: &DecisionDrainerConfig{Name: "node0", Namespace: "kube-system"},
+		},
+		{
+			name:   "case 2: look up drainerconfig with configured label selector",
+			target: DrainerConfigTarget{LabelSelector: "node=node0"},
+			drainerConfigs: []runtime.Object{
+				newDrainerConfig("foo", "bar", nil),
+				newDrainerConfig("abc", "bar", map[string]string{"node": "node0"}),
+				newDrainerConfig("def", "bar", map[string]string{"node": "node1"}),
+			},
+			expectedDrainerConfig: &DecisionDrainerConfig{Name: "abc", Namespace: "bar"},
+		},
+		{
+			name:   "case 3: find no drainerconfig when label selector matches none",
+			target: DrainerConfigTarget{LabelSelector: "node=node2"},
+			drainerConfigs: []runtime.Object{
+				newDrainerConfig("foo", "bar", nil),
+				newDrainerConfig("abc", "bar", map[string]string{"node": "node0"}),
+			},
+			expectedDrainerConfig: nil,
+		},
+		{
+			name:   "case 4: return tooManyDrainerConfigsError when label selector matches multiple",
+			target: DrainerConfigTarget{LabelSelector: "cluster=abc"},
+			drainerConfigs: []runtime.Object{
+				newDrainerConfig("abc", "bar", map[string]string{"cluster": "abc"}),
+				newDrainerConfig("def", "bar", map[string]string{"cluster": "abc"}),
+			},
+			errorMatcher: IsTooManyDrainerConfigs,
+		},
+		{
+			name:          "case 5: context target overrides configured target",
+			target:        DrainerConfigTarget{LabelSelector: "node=node0"},
+			contextTarget: &DrainerConfigTarget{Name: "def"},
+			drainerConfigs: []runtime.Object{
+				newDrainerConfig("abc", "bar", map[string]string{"node": "node0"}),
+				newDrainerConfig("def", "bar", nil),
+			},
+			expectedDrainerConfig: &DecisionDrainerConfig{Name: "def", Namespace: "bar"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			p, err := NewDrainerConfigProvider(DrainerConfigProviderConfig{
+				G8sClient: fake.NewSimpleClientset(tc.drainerConfigs...),
+				Logger:    microloggertest.New(),
+				Target:    tc.target,
+			})
+			if err != nil {
+				t.Fatalf("error == %#v, want nil", err)
+			}
+
+			ctx := context.Background()
+			if tc.contextTarget != nil {
+				ctx = NewDrainerConfigTargetContext(ctx, *tc.contextTarget)
+			}
+
+			d, err := p.Decide(ctx, Pod{Name: "foo", Namespace: "bar"})
+
+			switch {
+			case err == nil && tc.errorMatcher == nil:
+				// correct; carry on
+			case err != nil && tc.errorMatcher == nil:
+				t.Fatalf("error == %#v, want nil", err)
+			case err == nil && tc.errorMatcher != nil:
+				t.Fatalf("error == nil, want non-nil")
+			case !tc.errorMatcher(err):
+				t.Fatalf("error == %#v, want matching", err)
+			}
+
+			if tc.errorMatcher != nil {
+				return
+			}
+
+			if tc.expectedDrainerConfig == nil {
+				if d.DrainerConfig != nil {
+					t.Fatalf("d.DrainerConfig == %#v, want nil", d.DrainerConfig)
+				}
+				return
+			}
+			if d.DrainerConfig == nil {
+				t.Fatalf("d.DrainerConfig == nil, want %#v", tc.expectedDrainerConfig)
+			}
+			if d.DrainerConfig.Name != tc.expectedDrainerConfig.Name || d.DrainerConfig.Namespace != tc.expectedDrainerConfig.Namespace {
+				t.Fatalf("d.DrainerConfig == %#v, want %#v", d.DrainerConfig, tc.expectedDrainerConfig)
+			}
+		})
+	}
+}
+
+func Test_DrainerConfigTarget_Validate(t *testing.T) {
+	testCases := []struct {
+		name         string
+		target       DrainerConfigTarget
+		errorMatcher func(error) bool
+	}{
+		{
+			name:   "case 0: empty target is valid",
+			target: DrainerConfigTarget{},
+		},
+		{
+			name:   "case 1: name and namespace are valid",
+			target: DrainerConfigTarget{Name: "foo", Namespace: "bar"},
+		},
+		{
+			name:         "case 2: name and label selector are mutually exclusive",
+			target:       DrainerConfigTarget{Name: "foo", LabelSelector: "node=node0"},
+			errorMatcher: IsInvalidConfig,
+		},
+		{
+			name:         "case 3: invalid label selector",
+			target:       DrainerConfigTarget{LabelSelector: "node in (node0"},
+			errorMatcher: IsInvalidConfig,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.target.Validate()
+
+			switch {
+			case err == nil && tc.errorMatcher == nil:
+				// correct; carry on
+			case err != nil && tc.errorMatcher == nil:
+				t.Fatalf("error == %#v, want nil", err)
+			case err == nil && tc.errorMatcher != nil:
+				t.Fatalf("error == nil, want non-nil")
+			case !tc.errorMatcher(err):
+				t.Fatalf("error == %#v, want matching", err)
+			}
+		})
+	}
+}
+
+func Test_DrainerConfigProvider_Ready(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	pod := Pod{Name: "foo", Namespace: "bar"}
+
+	client := fake.NewSimpleClientset()
+	client.PrependReactor("get", "drainerconfigs", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		return true, nil, apierrors.NewForbidden(schema.GroupResource{Resource: "drainerconfigs"}, "", nil)
+	})
+
+	p, err := NewDrainerConfigProvider(DrainerConfigProviderConfig{
+		G8sClient: client,
+		Logger:    microloggertest.New(),
+	})
+	if err != nil {
+		t.Fatalf("error == %#v, want nil", err)
+	}
+
+	// The lookup before the informer is booted fails.
+	_, err = p.getDrainerConfig(ctx, p.resolveTarget(ctx, pod))
+	if err == nil {
+		t.Fatalf("error == nil, want non-nil")
+	}
+
+	err = p.Boot(ctx, pod)
+	if err != nil {
+		t.Fatalf("error == %#v, want nil", err)
+	}
+
+	// The failure is forgotten once the informer cache is synced.
+	deadline := time.Now().Add(5 * time.Second)
+	for p.Ready(ctx) != nil {
+		if time.Now().After(deadline) {
+			t.Fatalf("error == %#v, want nil", p.Ready(ctx))
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// Lookups of targets given by callers do not affect readiness.
+	targetCtx := NewDrainerConfigTargetContext(ctx, DrainerConfigTarget{Namespace: "forbidden"})
+	_, err = p.Decide(targetCtx, pod)
+	if err == nil {
+		t.Fatalf("error == nil, want non-nil")
+	}
+
+	err = p.Ready(ctx)
+	if err != nil {
+		t.Fatalf("error == %#v, want nil", err)
+	}
+}
@@ -4,6 +4,15 @@ import (
 	"github.com/giantswarm/microerror"
 )
 
+var bootFailedError = &microerror.Error{
+	Kind: "bootFailedError",
+}
+
+// IsBootFailed asserts bootFailedError.
+func IsBootFailed(err error) bool {
+	return microerror.Cause(err) == bootFailedError
+}
+
 var invalidConfigError = &microerror.Error{
 	Kind: "invalidConfigError",
 }
@@ -12,3 +21,57 @@ var invalidConfigError = &microerror.Error{
 func IsInvalidConfig(err error) bool {
 	return microerror.Cause(err) == invalidConfigError
 }
+
+var wrongTypeError = &microerror.Error{
+	Kind: "wrongTypeError",
+}
+
+// IsWrongType asserts wrongTypeError.
+func IsWrongType(err error) bool {
+	return microerror.Cause(err) == wrongTypeError
+}
+
+var invalidAnnotationError = &microerror.Error{
+	Kind: "invalidAnnotationError",
+}
+
+// IsInvalidAnnotation asserts invalidAnnotationError.
+func IsInvalidAnnotation(err error) bool {
+	return microerror.Cause(err) == invalidAnnotationError
+}
+
+var invalidRequestError = &microerror.Error{
+	Kind: "invalidRequestError",
+}
+
+// IsInvalidRequest asserts invalidRequestError.
+func IsInvalidRequest(err error) bool {
+	return microerror.Cause(err) == invalidRequestError
+}
+
+var notFoundError = &microerror.Error{
+	Kind: "notFoundError",
+}
+
+// IsNotFound asserts notFoundError.
+func IsNotFound(err error) bool {
+	return microerror.Cause(err) == notFoundError
+}
+
+var notReadyError = &microerror.Error{
+	Kind: "notReadyError",
+}
+
+// IsNotReady asserts notReadyError.
+func IsNotReady(err error) bool {
+	return microerror.Cause(err) == notReadyError
+}
+
+var tooManyDrainerConfigsError = &microerror.Error{
+	Kind: "tooManyDrainerConfigsError",
+}
+
+// IsTooManyDrainerConfigs asserts tooManyDrainerConfigsError.
+func IsTooManyDrainerConfigs(err error) bool {
+	return microerror.Cause(err) == tooManyDrainerConfigsError
+}
@@ -0,0 +1,148 @@
+package deferrer
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/giantswarm/apiextensions/pkg/apis/core/v1alpha1"
+	"github.com/giantswarm/microerror"
+	"github.com/giantswarm/micrologger"
+	corev1 "k8s.io/api/core/v1"
+	metasv1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
+)
+
+const (
+	// EventReasonLookupFailed is the reason of the Warning Event recorded when
+	// a provider starts failing to look up its signal.
+	EventReasonLookupFailed = "ShutdownDeferrerLookupFailed"
+	// EventReasonShutdownAllowed is the reason of the Event recorded when node
+	// termination is not deferred anymore, e.g. because the node is drained or
+	// the deadline is exceeded.
+	EventReasonShutdownAllowed = "ShutdownAllowed"
+	// EventReasonShutdownDeferred is the reason of the Event recorded when node
+	// termination is deferred for the first time.
+	EventReasonShutdownDeferred = "ShutdownDeferred"
+)
+
+const (
+	eventComponent = "shutdown-deferrer"
+)
+
+// EventRecorder records Kubernetes Events about decision transitions, so that
+// they outlive the POD for post-mortems.
+type EventRecorder interface {
+	// Event records an Event of the given type and reason on the given POD
+	// and, depending on the implementation, the DrainerConfig the decision is
+	// based on.
+	Event(ctx context.Context, pod Pod, decision Decision, eventType, reason, message string)
+}
+
+type KubernetesEventRecorderConfig struct {
+	K8sClient kubernetes.Interface
+	Logger    micrologger.Logger
+
+	// DrainerConfig makes the recorder also record Events on the DrainerConfig
+	// the decision is based on.
+	DrainerConfig bool
+	// NodeName is the name of the node the POD is running on. It is reported
+	// as the source host of the Events, if set.
+	NodeName string
+	// PodInformer is used to look up the PODs Events are recorded on, if set.
+	// PODs are requested from the API server otherwise.
+	PodInformer cache.SharedIndexInformer
+}
+
+// KubernetesEventRecorder records Events using the Kubernetes API.
+type KubernetesEventRecorder struct {
+	k8sClient   kubernetes.Interface
+	logger      micrologger.Logger
+	podInformer cache.SharedIndexInformer
+	recorder    record.EventRecorder
+
+	drainerConfig bool
+}
+
+func NewKubernetesEventRecorder(config KubernetesEventRecorderConfig) (*KubernetesEventRecorder, error) {
+	if config.K8sClient == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.K8sClient must not be empty", config)
+	}
+	if config.Logger == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Logger must not be empty", config)
+	}
+
+	broadcaster := record.NewBroadcaster()
+	broadcaster.StartRecordingToSink(&typedcorev1.EventSinkImpl{
+		Interface: config.K8sClient.CoreV1().Events(metasv1.NamespaceAll),
+	})
+
+	source := corev1.EventSource{
+		Component: eventComponent,
+		Host:      config.NodeName,
+	}
+
+	r := &KubernetesEventRecorder{
+		k8sClient:   config.K8sClient,
+		logger:      config.Logger,
+		podInformer: config.PodInformer,
+		recorder:    broadcaster.NewRecorder(scheme.Scheme, source),
+
+		drainerConfig: config.DrainerConfig,
+	}
+
+	return r, nil
+}
+
+// Permissions returns the permissions required to record Events on the given
+// POD, or on all PODs when the POD is empty.
+func (r *KubernetesEventRecorder) Permissions(pod Pod) []Permission {
+	var permissions []Permission
+	permissions = append(permissions, newPermissions("", "events", pod.Namespace, "create", "patch")...)
+	permissions = append(permissions, newPermissions("", "pods", pod.Namespace, "get")...)
+
+	return permissions
+}
+
+func (r *KubernetesEventRecorder) Event(ctx context.Context, pod Pod, decision Decision, eventType, reason, message string) {
+	r.recorder.Event(r.podReference(ctx, pod), eventType, reason, message)
+
+	if r.drainerConfig && decision.DrainerConfig != nil {
+		ref := &corev1.ObjectReference{
+			APIVersion:      v1alpha1.SchemeGroupVersion.String(),
+			Kind:            "DrainerConfig",
+			Name:            decision.DrainerConfig.Name,
+			Namespace:       decision.DrainerConfig.Namespace,
+			ResourceVersion: decision.DrainerConfig.ResourceVersion,
+		}
+
+		r.recorder.Event(ref, eventType, reason, message)
+	}
+}
+
+// podReference returns the reference of the given POD. The UID is looked up,
+// from the POD informer cache if available, because Events without it are not
+// shown when describing the POD. In case the lookup fails the reference is
+// returned without UID.
+func (r *KubernetesEventRecorder) podReference(ctx context.Context, pod Pod) *corev1.ObjectReference {
+	ref := &corev1.ObjectReference{
+		APIVersion: "v1",
+		Kind:       "Pod",
+		Name:       pod.Name,
+		Namespace:  pod.Namespace,
+	}
+
+	p, err := getPod(r.k8sClient, r.podInformer, pod)
+	if err != nil {
+		_ = r.logger.LogCtx(ctx, "level", "warning", "message", fmt.Sprintf("failed to look up pod %s/%s for recording event", pod.Namespace, pod.Name), "stack", fmt.Sprintf("%#v", err))
+		return ref
+	}
+
+	ref.UID = p.UID
+	ref.ResourceVersion = p.ResourceVersion
+
+	return ref
+}
@@ -0,0 +1,60 @@
+package deferrer
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	PrometheusNamespace = "shutdown_deferrer"
+	PrometheusSubsystem = "deferrer"
+)
+
+const (
+	lookupSourceAPIServer = "apiserver"
+	lookupSourceCache     = "cache"
+)
+
+var (
+	decisionCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: PrometheusNamespace,
+			Subsystem: PrometheusSubsystem,
+			Name:      "decision_total",
+			Help:      "Number of defer decisions by reason.",
+		},
+		[]string{"defer", "reason"},
+	)
+	deferredGauge = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Namespace: PrometheusNamespace,
+			Subsystem: PrometheusSubsystem,
+			Name:      "deferred_seconds",
+			Help:      "Time in seconds since node termination was deferred for the first time. Zero when termination is not deferred.",
+		},
+	)
+	lookupErrorCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: PrometheusNamespace,
+			Subsystem: PrometheusSubsystem,
+			Name:      "drainerconfig_lookup_error_total",
+			Help:      "Number of failed DrainerConfig lookups by error type.",
+		},
+		[]string{"source", "type"},
+	)
+	lookupHistogram = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: PrometheusNamespace,
+			Subsystem: PrometheusSubsystem,
+			Name:      "drainerconfig_lookup_duration_seconds",
+			Help:      "Histogram for the duration of DrainerConfig lookups.",
+		},
+		[]string{"source"},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(decisionCounter)
+	prometheus.MustRegister(deferredGauge)
+	prometheus.MustRegister(lookupErrorCounter)
+	prometheus.MustRegister(lookupHistogram)
+}
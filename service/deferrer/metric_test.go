@@ -0,0 +1,75 @@
+package deferrer
+
+import (
+	"context"
+	"testing"
+
+	"github.com/giantswarm/micrologger/microloggertest"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func Test_Decide_Metrics(t *testing.T) {
+	provider := &testProvider{name: "test"}
+
+	s, err := New(Config{
+		Logger:    microloggertest.New(),
+		Pod:       Pod{Name: "foo", Namespace: "bar"},
+		Providers: []Provider{provider},
+	})
+	if err != nil {
+		t.Fatalf("error == %#v, want nil", err)
+	}
+
+	testCases := []struct {
+		name             string
+		decision         Decision
+		expectedDefer    string
+		expectedDeferred bool
+	}{
+		{
+			name:             "case 0: count deferring decision and track deferred time",
+			decision:         Decision{Defer: true, Reason: ReasonNoConditions},
+			expectedDefer:    "true",
+			expectedDeferred: true,
+		},
+		{
+			name:             "case 1: count repeated deferring decision and keep tracking deferred time",
+			decision:         Decision{Defer: true, Reason: ReasonNoConditions},
+			expectedDefer:    "true",
+			expectedDeferred: true,
+		},
+		{
+			name:             "case 2: count allowing decision and reset deferred time",
+			decision:         Decision{Defer: false, Reason: ReasonDrained},
+			expectedDefer:    "false",
+			expectedDeferred: false,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			provider.decision = tc.decision
+
+			counter := decisionCounter.WithLabelValues(tc.expectedDefer, tc.decision.Reason)
+			before := testutil.ToFloat64(counter)
+
+			_, err := s.Decide(context.Background())
+			if err != nil {
+				t.Fatalf("error == %#v, want nil", err)
+			}
+
+			after := testutil.ToFloat64(counter)
+			if after-before != 1 {
+				t.Fatalf("decision_total{defer=%q,reason=%q} increased by %v, want 1", tc.expectedDefer, tc.decision.Reason, after-before)
+			}
+
+			deferred := testutil.ToFloat64(deferredGauge)
+			if tc.expectedDeferred && deferred <= 0 {
+				t.Fatalf("deferred_seconds == %v, want > 0", deferred)
+			}
+			if !tc.expectedDeferred && deferred != 0 {
+				t.Fatalf("deferred_seconds == %v, want 0", deferred)
+			}
+		})
+	}
+}
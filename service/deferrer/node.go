@@ -0,0 +1,115 @@
+package deferrer
+
+import (
+	"github.com/giantswarm/microerror"
+	corev1 "k8s.io/api/core/v1"
+	metasv1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+)
+
+const (
+	// EnvKeyMyNodeName is the environment variable the name of the node the
+	// POD is running on is read from. It can be set from spec.nodeName using
+	// the Kubernetes Downward API.
+	EnvKeyMyNodeName = "MY_NODE_NAME"
+)
+
+const (
+	// annotationMirrorPod is set by the kubelet on mirror pods of static pods,
+	// which cannot be evicted.
+	annotationMirrorPod = "kubernetes.io/config.mirror"
+)
+
+// newNodePodInformer returns an informer watching the pods scheduled to the
+// node with the given name. The informer is not started.
+func newNodePodInformer(k8sClient kubernetes.Interface, nodeName string) cache.SharedIndexInformer {
+	fieldSelector := fields.OneTermEqualSelector("spec.nodeName", nodeName).String()
+
+	lw := &cache.ListWatch{
+		ListFunc: func(options metasv1.ListOptions) (runtime.Object, error) {
+			options.FieldSelector = fieldSelector
+			return k8sClient.CoreV1().Pods(metasv1.NamespaceAll).List(options)
+		},
+		WatchFunc: func(options metasv1.ListOptions) (watch.Interface, error) {
+			options.FieldSelector = fieldSelector
+			return k8sClient.CoreV1().Pods(metasv1.NamespaceAll).Watch(options)
+		},
+	}
+
+	return cache.NewSharedIndexInformer(lw, &corev1.Pod{}, resyncPeriod, cache.Indexers{})
+}
+
+// getNodePods returns the pods scheduled to the node with the given name from
+// the informer cache when it is synced or from the API server otherwise.
+func getNodePods(k8sClient kubernetes.Interface, informer cache.SharedIndexInformer, nodeName string) ([]corev1.Pod, error) {
+	if informer != nil && informer.HasSynced() {
+		var pods []corev1.Pod
+		for _, obj := range informer.GetStore().List() {
+			pod, ok := obj.(*corev1.Pod)
+			if !ok {
+				return nil, microerror.Maskf(wrongTypeError, "expected %T, got %T", pod, obj)
+			}
+
+			pods = append(pods, *pod.DeepCopy())
+		}
+
+		return pods, nil
+	}
+
+	options := metasv1.ListOptions{
+		FieldSelector: fields.OneTermEqualSelector("spec.nodeName", nodeName).String(),
+	}
+
+	list, err := k8sClient.CoreV1().Pods(metasv1.NamespaceAll).List(options)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	return list.Items, nil
+}
+
+// evictablePods filters the given pods for the evictable pods on the node with
+// the given name, except the given POD.
+func evictablePods(pods []corev1.Pod, nodeName string, self Pod) []corev1.Pod {
+	var evictable []corev1.Pod
+	for _, pod := range pods {
+		if pod.Spec.NodeName != nodeName {
+			continue
+		}
+		if pod.GetName() == self.Name && pod.GetNamespace() == self.Namespace {
+			continue
+		}
+		if !isEvictable(pod) {
+			continue
+		}
+
+		evictable = append(evictable, pod)
+	}
+
+	return evictable
+}
+
+// isEvictable returns false for pods which are not evicted when draining a
+// node or which are already gone or going.
+func isEvictable(pod corev1.Pod) bool {
+	if pod.GetDeletionTimestamp() != nil {
+		return false
+	}
+	if pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+		return false
+	}
+	if _, ok := pod.GetAnnotations()[annotationMirrorPod]; ok {
+		return false
+	}
+	for _, ref := range pod.GetOwnerReferences() {
+		if ref.Controller != nil && *ref.Controller && ref.Kind == "DaemonSet" {
+			return false
+		}
+	}
+
+	return true
+}
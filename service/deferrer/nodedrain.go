@@ -0,0 +1,285 @@
+package deferrer
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/giantswarm/microerror"
+	"github.com/giantswarm/micrologger"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metasv1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+)
+
+const (
+	// ProviderNodeDrain is the name of the NodeDrainProvider.
+	ProviderNodeDrain = "nodedrain"
+)
+
+type NodeDrainProviderConfig struct {
+	K8sClient kubernetes.Interface
+	Logger    micrologger.Logger
+
+	// NodeName is the name of the node the POD is running on.
+	NodeName string
+}
+
+// NodeDrainProvider defers node termination until the node the POD is running
+// on is cordoned and no evictable pods other than the POD itself remain on
+// it. It only relies on core Kubernetes objects and can therefore be used in
+// clusters without the drainer operator.
+type NodeDrainProvider struct {
+	k8sClient kubernetes.Interface
+	logger    micrologger.Logger
+
+	nodeName string
+
+	changed      chan struct{}
+	nodeInformer cache.SharedIndexInformer
+	podInformer  cache.SharedIndexInformer
+	mutex        sync.RWMutex
+}
+
+func NewNodeDrainProvider(config NodeDrainProviderConfig) (*NodeDrainProvider, error) {
+	if config.K8sClient == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.K8sClient must not be empty", config)
+	}
+	if config.Logger == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Logger must not be empty", config)
+	}
+	if config.NodeName == "" {
+		return nil, microerror.Maskf(invalidConfigError, "%T.NodeName must not be empty", config)
+	}
+
+	p := &NodeDrainProvider{
+		k8sClient: config.K8sClient,
+		logger:    config.Logger,
+
+		nodeName: config.NodeName,
+
+		changed: make(chan struct{}),
+	}
+
+	return p, nil
+}
+
+// Boot starts informers watching the node the POD is running on and the pods
+// scheduled to that node. The informers are stopped once the given context is
+// done.
+func (p *NodeDrainProvider) Boot(ctx context.Context, pod Pod) error {
+	nodeName := p.nodeName
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if p.nodeInformer != nil {
+		return nil
+	}
+
+	handler := cache.ResourceEventHandlerFuncs{
+		AddFunc:    func(obj interface{}) { p.notify() },
+		UpdateFunc: func(oldObj, newObj interface{}) { p.notify() },
+		DeleteFunc: func(obj interface{}) { p.notify() },
+	}
+
+	{
+		fieldSelector := fields.OneTermEqualSelector("metadata.name", nodeName).String()
+
+		lw := &cache.ListWatch{
+			ListFunc: func(options metasv1.ListOptions) (runtime.Object, error) {
+				options.FieldSelector = fieldSelector
+				return p.k8sClient.CoreV1().Nodes().List(options)
+			},
+			WatchFunc: func(options metasv1.ListOptions) (watch.Interface, error) {
+				options.FieldSelector = fieldSelector
+				return p.k8sClient.CoreV1().Nodes().Watch(options)
+			},
+		}
+
+		p.nodeInformer = cache.NewSharedIndexInformer(lw, &corev1.Node{}, resyncPeriod, cache.Indexers{})
+		p.nodeInformer.AddEventHandler(handler)
+	}
+
+	{
+		p.podInformer = newNodePodInformer(p.k8sClient, nodeName)
+		p.podInformer.AddEventHandler(handler)
+	}
+
+	go p.nodeInformer.Run(ctx.Done())
+	go p.podInformer.Run(ctx.Done())
+
+	return nil
+}
+
+// Changes returns a channel which is closed on the next node or pod event
+// observed by the informers.
+func (p *NodeDrainProvider) Changes() <-chan struct{} {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+
+	return p.changed
+}
+
+// Decide checks if the node the given POD is running on is cordoned and if any
+// evictable pods remain on it. Pods owned by DaemonSets, mirror pods,
+// completed pods and pods which are already terminating are not considered
+// evictable, just like the given POD itself. Otherwise PODs using this provider
+// on the same node would wait for each other.
+func (p *NodeDrainProvider) Decide(ctx context.Context, pod Pod) (Decision, error) {
+	nodeName := p.nodeName
+
+	var node *corev1.Node
+	{
+		var err error
+
+		_ = p.logger.LogCtx(ctx, "level", "debug", "message", fmt.Sprintf("finding node %s", nodeName))
+
+		node, err = p.getNode(nodeName)
+		if err != nil {
+			return Decision{}, microerror.Mask(err)
+		}
+
+		if node == nil {
+			_ = p.logger.LogCtx(ctx, "level", "debug", "message", fmt.Sprintf("did not find node %s", nodeName))
+			_ = p.logger.LogCtx(ctx, "level", "debug", "message", "found node termination should be deferred")
+
+			d := Decision{
+				Defer:  true,
+				Reason: ReasonNodeNotFound,
+			}
+
+			return d, nil
+		}
+
+		_ = p.logger.LogCtx(ctx, "level", "debug", "message", fmt.Sprintf("found node %s", nodeName))
+	}
+
+	if !node.Spec.Unschedulable {
+		_ = p.logger.LogCtx(ctx, "level", "debug", "message", "found node termination should be deferred")
+		_ = p.logger.LogCtx(ctx, "level", "debug", "message", fmt.Sprintf("node %s is not cordoned", nodeName))
+
+		d := Decision{
+			Defer:  true,
+			Reason: ReasonNodeNotCordoned,
+		}
+
+		return d, nil
+	}
+
+	var remaining []corev1.Pod
+	{
+		p.mutex.RLock()
+		informer := p.podInformer
+		p.mutex.RUnlock()
+
+		pods, err := getNodePods(p.k8sClient, informer, nodeName)
+		if err != nil {
+			return Decision{}, microerror.Mask(err)
+		}
+
+		remaining = evictablePods(pods, nodeName, pod)
+	}
+
+	if len(remaining) > 0 {
+		_ = p.logger.LogCtx(ctx, "level", "debug", "message", "found node termination should be deferred")
+		_ = p.logger.LogCtx(ctx, "level", "debug", "message", fmt.Sprintf("%d evictable pods remain on node %s", len(remaining), nodeName))
+
+		d := Decision{
+			Defer:  true,
+			Reason: ReasonPodsRemaining,
+		}
+
+		return d, nil
+	}
+
+	_ = p.logger.LogCtx(ctx, "level", "debug", "message", "found node termination does not have to be deferred")
+	_ = p.logger.LogCtx(ctx, "level", "debug", "message", fmt.Sprintf("node %s is drained", nodeName))
+
+	d := Decision{
+		Defer:  false,
+		Reason: ReasonNodeDrained,
+	}
+
+	return d, nil
+}
+
+func (p *NodeDrainProvider) Name() string {
+	return ProviderNodeDrain
+}
+
+// Permissions returns the permissions required to look up and watch the node
+// and the pods scheduled to it.
+func (p *NodeDrainProvider) Permissions(pod Pod) []Permission {
+	var permissions []Permission
+	permissions = append(permissions, newPermissions("", "nodes", "", "get", "list", "watch")...)
+	permissions = append(permissions, newPermissions("", "pods", metasv1.NamespaceAll, "list", "watch")...)
+
+	return permissions
+}
+
+// Ready returns a notReadyError until the informer caches are synced.
+func (p *NodeDrainProvider) Ready(ctx context.Context) error {
+	p.mutex.RLock()
+	nodeInformer := p.nodeInformer
+	podInformer := p.podInformer
+	p.mutex.RUnlock()
+
+	if nodeInformer == nil || podInformer == nil {
+		return microerror.Maskf(notReadyError, "node and pod informers not booted")
+	}
+	if !nodeInformer.HasSynced() || !podInformer.HasSynced() {
+		return microerror.Maskf(notReadyError, "node and pod informer caches not synced")
+	}
+
+	return nil
+}
+
+// notify wakes up all goroutines waiting for node or pod changes.
+func (p *NodeDrainProvider) notify() {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	close(p.changed)
+	p.changed = make(chan struct{})
+}
+
+// getNode returns the node with the given name from the informer cache when it
+// is synced or from the API server otherwise. In case the node does not exist
+// nil is returned.
+func (p *NodeDrainProvider) getNode(name string) (*corev1.Node, error) {
+	p.mutex.RLock()
+	informer := p.nodeInformer
+	p.mutex.RUnlock()
+
+	if informer != nil && informer.HasSynced() {
+		obj, exists, err := informer.GetStore().GetByKey(name)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+		if !exists {
+			return nil, nil
+		}
+
+		node, ok := obj.(*corev1.Node)
+		if !ok {
+			return nil, microerror.Maskf(wrongTypeError, "expected %T, got %T", node, obj)
+		}
+
+		return node.DeepCopy(), nil
+	}
+
+	node, err := p.k8sClient.CoreV1().Nodes().Get(name, metasv1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	return node, nil
+}
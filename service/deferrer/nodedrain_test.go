@@ -0,0 +1,148 @@
+package deferrer
+
+import (
+	"context"
+	"testing"
+
+	"github.com/giantswarm/micrologger/microloggertest"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func Test_NodeDrainProvider_Decide(t *testing.T) {
+	controller := true
+	deletionTimestamp := metav1.Now()
+
+	newNode := func(unschedulable bool) *corev1.Node {
+		return &corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "node0",
+			},
+			Spec: corev1.NodeSpec{
+				Unschedulable: unschedulable,
+			},
+		}
+	}
+	newPod := func(name string, nodeName string) *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: "bar",
+			},
+			Spec: corev1.PodSpec{
+				NodeName: nodeName,
+			},
+			Status: corev1.PodStatus{
+				Phase: corev1.PodRunning,
+			},
+		}
+	}
+
+	testCases := []struct {
+		name                string
+		objects             []runtime.Object
+		expectedShouldDefer bool
+		expectedReason      string
+	}{
+		{
+			name:                "case 0: should defer without node",
+			objects:             nil,
+			expectedShouldDefer: true,
+			expectedReason:      ReasonNodeNotFound,
+		},
+		{
+			name: "case 1: should defer with node that is not cordoned",
+			objects: []runtime.Object{
+				newNode(false),
+				newPod("foo", "node0"),
+			},
+			expectedShouldDefer: true,
+			expectedReason:      ReasonNodeNotCordoned,
+		},
+		{
+			name: "case 2: should defer with cordoned node that has evictable pods",
+			objects: []runtime.Object{
+				newNode(true),
+				newPod("foo", "node0"),
+				newPod("baz", "node0"),
+			},
+			expectedShouldDefer: true,
+			expectedReason:      ReasonPodsRemaining,
+		},
+		{
+			name: "case 3: should not defer with cordoned node that only has the pod itself",
+			objects: []runtime.Object{
+				newNode(true),
+				newPod("foo", "node0"),
+				newPod("baz", "node1"),
+			},
+			expectedShouldDefer: false,
+			expectedReason:      ReasonNodeDrained,
+		},
+		{
+			name: "case 4: should not defer with cordoned node that only has pods which are not evictable",
+			objects: []runtime.Object{
+				newNode(true),
+				newPod("foo", "node0"),
+				func() *corev1.Pod {
+					p := newPod("daemonset", "node0")
+					p.OwnerReferences = []metav1.OwnerReference{
+						{Kind: "DaemonSet", Name: "ds", Controller: &controller},
+					}
+					return p
+				}(),
+				func() *corev1.Pod {
+					p := newPod("mirror", "node0")
+					p.Annotations = map[string]string{annotationMirrorPod: "hash"}
+					return p
+				}(),
+				func() *corev1.Pod {
+					p := newPod("completed", "node0")
+					p.Status.Phase = corev1.PodSucceeded
+					return p
+				}(),
+				func() *corev1.Pod {
+					p := newPod("terminating", "node0")
+					p.DeletionTimestamp = &deletionTimestamp
+					return p
+				}(),
+			},
+			expectedShouldDefer: false,
+			expectedReason:      ReasonNodeDrained,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var p *NodeDrainProvider
+			{
+				c := NodeDrainProviderConfig{
+					K8sClient: fake.NewSimpleClientset(tc.objects...),
+					Logger:    microloggertest.New(),
+
+					NodeName: "node0",
+				}
+
+				var err error
+				p, err = NewNodeDrainProvider(c)
+				if err != nil {
+					t.Fatalf("error == %#v, want nil", err)
+				}
+			}
+
+			d, err := p.Decide(context.Background(), Pod{Name: "foo", Namespace: "bar"})
+			if err != nil {
+				t.Fatalf("error == %#v, want nil", err)
+			}
+
+			if d.Defer != tc.expectedShouldDefer {
+				t.Fatalf("d.Defer == %v, want %v", d.Defer, tc.expectedShouldDefer)
+			}
+			if d.Reason != tc.expectedReason {
+				t.Fatalf("d.Reason == %q, want %q", d.Reason, tc.expectedReason)
+			}
+		})
+	}
+}
@@ -0,0 +1,197 @@
+package deferrer
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/giantswarm/microerror"
+)
+
+const (
+	// AnnotationOverride is the annotation persisting the Override of the
+	// node termination of a POD, set using SetOverride. Its value is the JSON
+	// representation of the Override.
+	AnnotationOverride = "shutdown-deferrer.giantswarm.io/override"
+)
+
+const (
+	// ForceAllow allows node termination regardless of the providers.
+	ForceAllow = "allow"
+	// ForceDefer defers node termination regardless of the providers and the
+	// deadline.
+	ForceDefer = "defer"
+)
+
+const (
+	// ProviderOverride is the provider name of decisions made because of an
+	// Override.
+	ProviderOverride = "override"
+)
+
+const (
+	// OverrideTargetDrainerConfig persists overrides on the DrainerConfig of
+	// the POD.
+	OverrideTargetDrainerConfig = "drainerconfig"
+	// OverrideTargetPod persists overrides on the POD.
+	OverrideTargetPod = "pod"
+)
+
+// Override forces node termination to be allowed or deferred until it
+// expires, e.g. to let a stuck VM die or to hold one wrongly marked drained.
+type Override struct {
+	// Force is one of ForceAllow or ForceDefer.
+	Force string `json:"force"`
+	// Reason explains why the override was set.
+	Reason string `json:"reason"`
+	// Expires is the time after which the override is ignored.
+	Expires time.Time `json:"expires"`
+	// User is the authenticated client which set the override, if known.
+	User string `json:"user,omitempty"`
+}
+
+// Validate returns an invalidConfigError when the override is malformed.
+func (o Override) Validate() error {
+	if o.Force != ForceAllow && o.Force != ForceDefer {
+		return microerror.Maskf(invalidConfigError, "%T.Force must be one of %q or %q", o, ForceAllow, ForceDefer)
+	}
+	if o.Reason == "" {
+		return microerror.Maskf(invalidConfigError, "%T.Reason must not be empty", o)
+	}
+	if o.Expires.IsZero() {
+		return microerror.Maskf(invalidConfigError, "%T.Expires must not be empty", o)
+	}
+
+	return nil
+}
+
+// Expired returns true when the override expired at the given time.
+func (o Override) Expired(now time.Time) bool {
+	return !now.Before(o.Expires)
+}
+
+// OverrideStore persists overrides, e.g. as annotation of a Kubernetes
+// object.
+type OverrideStore interface {
+	// Boot starts watching the override of the given POD, or of all PODs when
+	// the given POD is empty. Background work is stopped once the given
+	// context is done.
+	Boot(ctx context.Context, pod Pod) error
+	// Changes returns a channel which is closed on the next change of a
+	// persisted override.
+	Changes() <-chan struct{}
+	// Delete removes the override of the given POD.
+	Delete(ctx context.Context, pod Pod) error
+	// Get returns the override of the given POD, or nil if there is none.
+	// Expired overrides are returned as well.
+	Get(ctx context.Context, pod Pod) (*Override, error)
+	// Set persists the given override of the given POD.
+	Set(ctx context.Context, pod Pod, override Override) error
+}
+
+// PodAnnotationSource provides the annotations of PODs, e.g. to honour the
+// AnnotationForce and AnnotationMaxDefer annotations.
+type PodAnnotationSource interface {
+	// Boot starts watching the annotations of the given POD, or of all PODs
+	// when the given POD is empty. Background work is stopped once the given
+	// context is done.
+	Boot(ctx context.Context, pod Pod) error
+	// Annotations returns the annotations of the given POD, or nil if the
+	// POD does not exist.
+	Annotations(ctx context.Context, pod Pod) (map[string]string, error)
+	// Changes returns a channel which is closed on the next change of the
+	// shutdown-deferrer annotations of the POD.
+	Changes() <-chan struct{}
+}
+
+// annotationPrefix is the prefix of all annotations honoured by the
+// deferrer.
+const annotationPrefix = "shutdown-deferrer.giantswarm.io/"
+
+// annotationsChanged returns true when any annotation with annotationPrefix
+// differs between the given annotations.
+func annotationsChanged(oldAnnotations, newAnnotations map[string]string) bool {
+	for _, annotations := range []map[string]string{oldAnnotations, newAnnotations} {
+		for k := range annotations {
+			if strings.HasPrefix(k, annotationPrefix) && oldAnnotations[k] != newAnnotations[k] {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// parseForce returns the given AnnotationForce annotation value or an
+// invalidAnnotationError when it is neither ForceAllow nor ForceDefer.
+func parseForce(v string) (string, error) {
+	if v != ForceAllow && v != ForceDefer {
+		return "", microerror.Maskf(invalidAnnotationError, "%s annotation value %q must be one of %q or %q", AnnotationForce, v, ForceAllow, ForceDefer)
+	}
+
+	return v, nil
+}
+
+// parseMaxDefer returns the duration of the given AnnotationMaxDefer
+// annotation value or an invalidAnnotationError when it is not a positive
+// duration.
+func parseMaxDefer(v string) (time.Duration, error) {
+	d, err := time.ParseDuration(v)
+	if err != nil || d <= 0 {
+		return 0, microerror.Maskf(invalidAnnotationError, "%s annotation value %q must be a positive duration like 10m", AnnotationMaxDefer, v)
+	}
+
+	return d, nil
+}
+
+// parseOverride returns the override stored in the given annotations, or nil
+// if there is none.
+func parseOverride(annotations map[string]string) (*Override, error) {
+	v, ok := annotations[AnnotationOverride]
+	if !ok {
+		return nil, nil
+	}
+
+	var o Override
+	err := json.Unmarshal([]byte(v), &o)
+	if err != nil {
+		return nil, microerror.Maskf(invalidAnnotationError, "%s annotation: %s", AnnotationOverride, err)
+	}
+
+	err = o.Validate()
+	if err != nil {
+		return nil, microerror.Maskf(invalidAnnotationError, "%s annotation: %s", AnnotationOverride, err)
+	}
+
+	return &o, nil
+}
+
+// newOverridePatch returns a JSON merge patch setting the override annotation
+// to the given override, or removing it when the override is nil.
+func newOverridePatch(override *Override) ([]byte, error) {
+	var value interface{}
+	if override != nil {
+		b, err := json.Marshal(override)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+
+		value = string(b)
+	}
+
+	patch := map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"annotations": map[string]interface{}{
+				AnnotationOverride: value,
+			},
+		},
+	}
+
+	b, err := json.Marshal(patch)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	return b, nil
+}
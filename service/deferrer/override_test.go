@@ -0,0 +1,295 @@
+package deferrer
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/giantswarm/apiextensions/pkg/apis/core/v1alpha1"
+	g8sfake "github.com/giantswarm/apiextensions/pkg/clientset/versioned/fake"
+	"github.com/giantswarm/micrologger/microloggertest"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func Test_Decide_Override(t *testing.T) {
+	deferring := &testProvider{name: "deferring", decision: Decision{Defer: true, Reason: ReasonNoConditions}}
+	allowing := &testProvider{name: "allowing", decision: Decision{Defer: false, Reason: ReasonDrained}}
+
+	testCases := []struct {
+		name                string
+		annotation          string
+		provider            Provider
+		expectedShouldDefer bool
+		expectedReason      string
+	}{
+		{
+			name:                "case 0: provider decides without override",
+			annotation:          "",
+			provider:            deferring,
+			expectedShouldDefer: true,
+			expectedReason:      ReasonNoConditions,
+		},
+		{
+			name:                "case 1: override allowing takes precedence over deferring provider",
+			annotation:          `{"force":"allow","reason":"stuck","expires":"2999-01-01T00:00:00Z"}`,
+			provider:            deferring,
+			expectedShouldDefer: false,
+			expectedReason:      ReasonOverride,
+		},
+		{
+			name:                "case 2: override deferring takes precedence over allowing provider",
+			annotation:          `{"force":"defer","reason":"debugging","expires":"2999-01-01T00:00:00Z"}`,
+			provider:            allowing,
+			expectedShouldDefer: true,
+			expectedReason:      ReasonOverride,
+		},
+		{
+			name:                "case 3: provider decides with expired override",
+			annotation:          `{"force":"defer","reason":"debugging","expires":"2000-01-01T00:00:00Z"}`,
+			provider:            allowing,
+			expectedShouldDefer: false,
+			expectedReason:      ReasonDrained,
+		},
+		{
+			name:                "case 4: provider decides with invalid override",
+			annotation:          `{"force":"maybe"}`,
+			provider:            deferring,
+			expectedShouldDefer: true,
+			expectedReason:      ReasonNoConditions,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			pod := &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "foo",
+					Namespace: "bar",
+				},
+			}
+			if tc.annotation != "" {
+				pod.Annotations = map[string]string{AnnotationOverride: tc.annotation}
+			}
+
+			store, err := NewPodOverrideStore(PodOverrideStoreConfig{
+				K8sClient: fake.NewSimpleClientset(pod),
+				Logger:    microloggertest.New(),
+			})
+			if err != nil {
+				t.Fatalf("error == %#v, want nil", err)
+			}
+
+			s, err := New(Config{
+				Logger:    microloggertest.New(),
+				Overrides: store,
+				Pod:       Pod{Name: "foo", Namespace: "bar"},
+				Providers: []Provider{tc.provider},
+			})
+			if err != nil {
+				t.Fatalf("error == %#v, want nil", err)
+			}
+
+			decision, err := s.Decide(context.TODO())
+			if err != nil {
+				t.Fatalf("error == %#v, want nil", err)
+			}
+
+			if decision.Defer != tc.expectedShouldDefer {
+				t.Fatalf("Decide().Defer == %v, want %v", decision.Defer, tc.expectedShouldDefer)
+			}
+			if decision.Reason != tc.expectedReason {
+				t.Fatalf("Decide().Reason == %q, want %q", decision.Reason, tc.expectedReason)
+			}
+			if (decision.Reason == ReasonOverride) != (decision.Override != nil) {
+				t.Fatalf("Decide().Override == %#v, want override only for reason %q", decision.Override, ReasonOverride)
+			}
+		})
+	}
+}
+
+func Test_SetOverride(t *testing.T) {
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "foo",
+			Namespace: "bar",
+		},
+	}
+
+	store, err := NewPodOverrideStore(PodOverrideStoreConfig{
+		K8sClient: fake.NewSimpleClientset(pod),
+		Logger:    microloggertest.New(),
+	})
+	if err != nil {
+		t.Fatalf("error == %#v, want nil", err)
+	}
+
+	s, err := New(Config{
+		Logger:    microloggertest.New(),
+		Overrides: store,
+		Pod:       Pod{Name: "foo", Namespace: "bar"},
+		Providers: []Provider{&testProvider{name: "deferring", decision: Decision{Defer: true, Reason: ReasonNoConditions}}},
+	})
+	if err != nil {
+		t.Fatalf("error == %#v, want nil", err)
+	}
+
+	err = s.SetOverride(context.TODO(), Override{Force: ForceDefer})
+	if !IsInvalidConfig(err) {
+		t.Fatalf("error == %#v, want matching", err)
+	}
+
+	override := Override{
+		Force:   ForceAllow,
+		Reason:  "stuck",
+		Expires: time.Now().Add(time.Hour).UTC().Truncate(time.Second),
+		User:    "admin",
+	}
+
+	err = s.SetOverride(context.TODO(), override)
+	if err != nil {
+		t.Fatalf("error == %#v, want nil", err)
+	}
+
+	stored, err := s.Override(context.TODO())
+	if err != nil {
+		t.Fatalf("error == %#v, want nil", err)
+	}
+	if stored == nil || !stored.Expires.Equal(override.Expires) || stored.Force != override.Force || stored.User != override.User {
+		t.Fatalf("Override() == %#v, want %#v", stored, override)
+	}
+
+	decision, err := s.Decide(context.TODO())
+	if err != nil {
+		t.Fatalf("error == %#v, want nil", err)
+	}
+	if decision.Defer {
+		t.Fatalf("Decide().Defer == %v, want %v", decision.Defer, false)
+	}
+
+	err = s.DeleteOverride(context.TODO())
+	if err != nil {
+		t.Fatalf("error == %#v, want nil", err)
+	}
+
+	stored, err = s.Override(context.TODO())
+	if err != nil {
+		t.Fatalf("error == %#v, want nil", err)
+	}
+	if stored != nil {
+		t.Fatalf("Override() == %#v, want nil", stored)
+	}
+}
+
+func Test_Decide_ForceAnnotation(t *testing.T) {
+	testCases := []struct {
+		name                     string
+		podAnnotations           map[string]string
+		drainerConfigAnnotations map[string]string
+		expectedShouldDefer      bool
+		expectedReason           string
+		expectedProvider         string
+	}{
+		{
+			name:                     "case 0: pod annotation forces node termination to be allowed",
+			podAnnotations:           map[string]string{AnnotationForce: ForceAllow},
+			drainerConfigAnnotations: nil,
+			expectedShouldDefer:      false,
+			expectedReason:           ReasonForced,
+			expectedProvider:         ProviderOverride,
+		},
+		{
+			name:                     "case 1: drainerconfig annotation forces node termination to be allowed",
+			podAnnotations:           nil,
+			drainerConfigAnnotations: map[string]string{AnnotationForce: ForceAllow},
+			expectedShouldDefer:      false,
+			expectedReason:           ReasonForced,
+			expectedProvider:         ProviderDrainerConfig,
+		},
+		{
+			name:                     "case 2: pod annotation takes precedence over drainerconfig annotation",
+			podAnnotations:           map[string]string{AnnotationForce: ForceDefer},
+			drainerConfigAnnotations: map[string]string{AnnotationForce: ForceAllow},
+			expectedShouldDefer:      true,
+			expectedReason:           ReasonForced,
+			expectedProvider:         ProviderOverride,
+		},
+		{
+			name:                     "case 3: invalid pod annotation is ignored",
+			podAnnotations:           map[string]string{AnnotationForce: "maybe"},
+			drainerConfigAnnotations: nil,
+			expectedShouldDefer:      true,
+			expectedReason:           ReasonNoConditions,
+			expectedProvider:         ProviderDrainerConfig,
+		},
+		{
+			name:                     "case 4: pod max-defer annotation takes precedence over drainerconfig annotation",
+			podAnnotations:           map[string]string{AnnotationMaxDefer: "1ns"},
+			drainerConfigAnnotations: map[string]string{AnnotationMaxDefer: "1h"},
+			expectedShouldDefer:      false,
+			expectedReason:           ReasonDeadlineExceeded,
+			expectedProvider:         ProviderDrainerConfig,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			pod := &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        "foo",
+					Namespace:   "bar",
+					Annotations: tc.podAnnotations,
+				},
+			}
+			drainerConfig := &v1alpha1.DrainerConfig{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        "foo",
+					Namespace:   "bar",
+					Annotations: tc.drainerConfigAnnotations,
+				},
+			}
+
+			store, err := NewPodOverrideStore(PodOverrideStoreConfig{
+				K8sClient: fake.NewSimpleClientset(pod),
+				Logger:    microloggertest.New(),
+			})
+			if err != nil {
+				t.Fatalf("error == %#v, want nil", err)
+			}
+
+			provider, err := NewDrainerConfigProvider(DrainerConfigProviderConfig{
+				G8sClient: g8sfake.NewSimpleClientset(drainerConfig),
+				Logger:    microloggertest.New(),
+			})
+			if err != nil {
+				t.Fatalf("error == %#v, want nil", err)
+			}
+
+			s, err := New(Config{
+				Logger:         microloggertest.New(),
+				Pod:            Pod{Name: "foo", Namespace: "bar"},
+				PodAnnotations: store,
+				Providers:      []Provider{provider},
+			})
+			if err != nil {
+				t.Fatalf("error == %#v, want nil", err)
+			}
+
+			decision, err := s.Decide(context.TODO())
+			if err != nil {
+				t.Fatalf("error == %#v, want nil", err)
+			}
+
+			if decision.Defer != tc.expectedShouldDefer {
+				t.Fatalf("Decide().Defer == %v, want %v", decision.Defer, tc.expectedShouldDefer)
+			}
+			if decision.Reason != tc.expectedReason {
+				t.Fatalf("Decide().Reason == %q, want %q", decision.Reason, tc.expectedReason)
+			}
+			if decision.Provider != tc.expectedProvider {
+				t.Fatalf("Decide().Provider == %q, want %q", decision.Provider, tc.expectedProvider)
+			}
+		})
+	}
+}
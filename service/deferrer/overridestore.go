@@ -0,0 +1,379 @@
+package deferrer
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/giantswarm/apiextensions/pkg/apis/core/v1alpha1"
+	"github.com/giantswarm/microerror"
+	"github.com/giantswarm/micrologger"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metasv1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+)
+
+type PodOverrideStoreConfig struct {
+	K8sClient kubernetes.Interface
+	Logger    micrologger.Logger
+}
+
+// PodOverrideStore persists overrides as AnnotationOverride annotation of the
+// POD itself. It also serves as PodAnnotationSource.
+type PodOverrideStore struct {
+	k8sClient kubernetes.Interface
+	logger    micrologger.Logger
+
+	changed  chan struct{}
+	informer cache.SharedIndexInformer
+	mutex    sync.RWMutex
+}
+
+func NewPodOverrideStore(config PodOverrideStoreConfig) (*PodOverrideStore, error) {
+	if config.K8sClient == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.K8sClient must not be empty", config)
+	}
+	if config.Logger == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Logger must not be empty", config)
+	}
+
+	s := &PodOverrideStore{
+		k8sClient: config.K8sClient,
+		logger:    config.Logger,
+
+		changed: make(chan struct{}),
+	}
+
+	return s, nil
+}
+
+// Boot starts an informer watching the given POD, so that changes of its
+// annotations are observed. No informer is started when the given POD is empty,
+// as in multi-tenant mode, and overrides are fetched from the API server
+// instead.
+func (s *PodOverrideStore) Boot(ctx context.Context, pod Pod) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.informer != nil || pod == (Pod{}) {
+		return nil
+	}
+
+	setSelectors := func(options *metasv1.ListOptions) {
+		options.FieldSelector = fields.OneTermEqualSelector("metadata.name", pod.Name).String()
+	}
+
+	lw := &cache.ListWatch{
+		ListFunc: func(options metasv1.ListOptions) (runtime.Object, error) {
+			setSelectors(&options)
+			return s.k8sClient.CoreV1().Pods(pod.Namespace).List(options)
+		},
+		WatchFunc: func(options metasv1.ListOptions) (watch.Interface, error) {
+			setSelectors(&options)
+			return s.k8sClient.CoreV1().Pods(pod.Namespace).Watch(options)
+		},
+	}
+
+	s.informer = cache.NewSharedIndexInformer(lw, &corev1.Pod{}, resyncPeriod, cache.Indexers{})
+	s.informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) { s.notify() },
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			// POD status updates are frequent and do not concern us.
+			oldPod, ok := oldObj.(*corev1.Pod)
+			if !ok {
+				return
+			}
+			newPod, ok := newObj.(*corev1.Pod)
+			if !ok {
+				return
+			}
+			if annotationsChanged(oldPod.Annotations, newPod.Annotations) {
+				s.notify()
+			}
+		},
+		DeleteFunc: func(obj interface{}) { s.notify() },
+	})
+
+	go s.informer.Run(ctx.Done())
+
+	return nil
+}
+
+// Annotations returns the annotations of the given POD, read like in Get.
+func (s *PodOverrideStore) Annotations(ctx context.Context, pod Pod) (map[string]string, error) {
+	p, err := s.getPod(pod)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+	if p == nil {
+		return nil, nil
+	}
+
+	return p.GetAnnotations(), nil
+}
+
+// Changes returns a channel which is closed on the next change of the
+// shutdown-deferrer annotations observed by the informer.
+func (s *PodOverrideStore) Changes() <-chan struct{} {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	return s.changed
+}
+
+func (s *PodOverrideStore) Delete(ctx context.Context, pod Pod) error {
+	err := s.patch(ctx, pod, nil)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	return nil
+}
+
+// Get returns the override of the given POD. Once Boot has been called for
+// the POD and the informer cache is synced, the POD is read from the cache.
+// Otherwise it is fetched from the API server.
+func (s *PodOverrideStore) Get(ctx context.Context, pod Pod) (*Override, error) {
+	p, err := s.getPod(pod)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+	if p == nil {
+		return nil, nil
+	}
+
+	override, err := parseOverride(p.GetAnnotations())
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	return override, nil
+}
+
+// Permissions returns the permissions required to read, watch and annotate
+// the given POD, or all PODs when the POD is empty.
+func (s *PodOverrideStore) Permissions(pod Pod) []Permission {
+	return newPermissions("", "pods", pod.Namespace, "get", "list", "patch", "watch")
+}
+
+func (s *PodOverrideStore) Set(ctx context.Context, pod Pod, override Override) error {
+	err := override.Validate()
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	err = s.patch(ctx, pod, &override)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	return nil
+}
+
+func (s *PodOverrideStore) getPod(pod Pod) (*corev1.Pod, error) {
+	s.mutex.RLock()
+	informer := s.informer
+	s.mutex.RUnlock()
+
+	if informer != nil && informer.HasSynced() {
+		obj, exists, err := informer.GetStore().GetByKey(fmt.Sprintf("%s/%s", pod.Namespace, pod.Name))
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+		if exists {
+			p, ok := obj.(*corev1.Pod)
+			if !ok {
+				return nil, microerror.Maskf(wrongTypeError, "expected %T, got %T", p, obj)
+			}
+
+			return p, nil
+		}
+	}
+
+	p, err := s.k8sClient.CoreV1().Pods(pod.Namespace).Get(pod.Name, metasv1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	return p, nil
+}
+
+func (s *PodOverrideStore) notify() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	close(s.changed)
+	s.changed = make(chan struct{})
+}
+
+func (s *PodOverrideStore) patch(ctx context.Context, pod Pod, override *Override) error {
+	_ = s.logger.LogCtx(ctx, "level", "debug", "message", fmt.Sprintf("patching override of pod %s/%s", pod.Namespace, pod.Name))
+
+	patch, err := newOverridePatch(override)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	_, err = s.k8sClient.CoreV1().Pods(pod.Namespace).Patch(pod.Name, types.MergePatchType, patch)
+	if apierrors.IsNotFound(err) {
+		return microerror.Maskf(notFoundError, "pod %s/%s", pod.Namespace, pod.Name)
+	} else if err != nil {
+		return microerror.Mask(err)
+	}
+
+	_ = s.logger.LogCtx(ctx, "level", "debug", "message", fmt.Sprintf("patched override of pod %s/%s", pod.Namespace, pod.Name))
+
+	return nil
+}
+
+type DrainerConfigOverrideStoreConfig struct {
+	Logger micrologger.Logger
+	// Provider is used to look up and watch the DrainerConfig of the POD, so
+	// that overrides are persisted on the same DrainerConfig decisions are
+	// based on.
+	Provider *DrainerConfigProvider
+}
+
+// DrainerConfigOverrideStore persists overrides as AnnotationOverride
+// annotation of the DrainerConfig of the POD.
+type DrainerConfigOverrideStore struct {
+	logger   micrologger.Logger
+	provider *DrainerConfigProvider
+}
+
+func NewDrainerConfigOverrideStore(config DrainerConfigOverrideStoreConfig) (*DrainerConfigOverrideStore, error) {
+	if config.Logger == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Logger must not be empty", config)
+	}
+	if config.Provider == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Provider must not be empty", config)
+	}
+
+	s := &DrainerConfigOverrideStore{
+		logger:   config.Logger,
+		provider: config.Provider,
+	}
+
+	return s, nil
+}
+
+// Boot boots the provider, which is a no-op when it has been booted already.
+func (s *DrainerConfigOverrideStore) Boot(ctx context.Context, pod Pod) error {
+	err := s.provider.Boot(ctx, pod)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	return nil
+}
+
+func (s *DrainerConfigOverrideStore) Changes() <-chan struct{} {
+	return s.provider.Changes()
+}
+
+func (s *DrainerConfigOverrideStore) Delete(ctx context.Context, pod Pod) error {
+	err := s.patch(ctx, pod, nil)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	return nil
+}
+
+func (s *DrainerConfigOverrideStore) Get(ctx context.Context, pod Pod) (*Override, error) {
+	drainerConfig, err := s.getDrainerConfig(ctx, pod)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+	if drainerConfig == nil {
+		return nil, nil
+	}
+
+	override, err := parseOverride(drainerConfig.GetAnnotations())
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	return override, nil
+}
+
+// Permissions returns the permissions required to annotate the DrainerConfig
+// of the given POD in addition to the ones of the provider.
+func (s *DrainerConfigOverrideStore) Permissions(pod Pod) []Permission {
+	namespace := metasv1.NamespaceAll
+	if pod != (Pod{}) {
+		namespace = s.provider.resolveTarget(context.Background(), pod).Namespace
+	}
+
+	permissions := s.provider.Permissions(pod)
+	permissions = append(permissions, newPermissions(v1alpha1.SchemeGroupVersion.Group, "drainerconfigs", namespace, "patch")...)
+
+	return permissions
+}
+
+func (s *DrainerConfigOverrideStore) Set(ctx context.Context, pod Pod, override Override) error {
+	err := override.Validate()
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	err = s.patch(ctx, pod, &override)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	return nil
+}
+
+func (s *DrainerConfigOverrideStore) getDrainerConfig(ctx context.Context, pod Pod) (*v1alpha1.DrainerConfig, error) {
+	target := s.provider.resolveTarget(ctx, pod)
+	err := target.Validate()
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	drainerConfig, err := s.provider.getDrainerConfig(ctx, target)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	return drainerConfig, nil
+}
+
+// patch looks up the DrainerConfig first, because the configured target may be
+// a label selector while patching requires its name.
+func (s *DrainerConfigOverrideStore) patch(ctx context.Context, pod Pod, override *Override) error {
+	drainerConfig, err := s.getDrainerConfig(ctx, pod)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+	if drainerConfig == nil {
+		return microerror.Maskf(notFoundError, "drainerconfig of pod %s/%s", pod.Namespace, pod.Name)
+	}
+
+	_ = s.logger.LogCtx(ctx, "level", "debug", "message", fmt.Sprintf("patching override of drainerconfig %s/%s", drainerConfig.Namespace, drainerConfig.Name))
+
+	patch, err := newOverridePatch(override)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	_, err = s.provider.g8sClient.CoreV1alpha1().DrainerConfigs(drainerConfig.Namespace).Patch(drainerConfig.Name, types.MergePatchType, patch)
+	if apierrors.IsNotFound(err) {
+		return microerror.Maskf(notFoundError, "drainerconfig %s/%s", drainerConfig.Namespace, drainerConfig.Name)
+	} else if err != nil {
+		return microerror.Mask(err)
+	}
+
+	_ = s.logger.LogCtx(ctx, "level", "debug", "message", fmt.Sprintf("patched override of drainerconfig %s/%s", drainerConfig.Namespace, drainerConfig.Name))
+
+	return nil
+}
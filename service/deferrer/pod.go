@@ -0,0 +1,74 @@
+package deferrer
+
+import (
+	"github.com/giantswarm/microerror"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metasv1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+)
+
+// NewPodInformer returns an informer watching all PODs. It is used in
+// multi-tenant mode, so that PODs do not have to be requested from the API
+// server for every decision or Event. The informer is not started. It is run
+// by Service.Boot when it is given to Config.PodInformer.
+func NewPodInformer(k8sClient kubernetes.Interface) cache.SharedIndexInformer {
+	lw := &cache.ListWatch{
+		ListFunc: func(options metasv1.ListOptions) (runtime.Object, error) {
+			return k8sClient.CoreV1().Pods(metasv1.NamespaceAll).List(options)
+		},
+		WatchFunc: func(options metasv1.ListOptions) (watch.Interface, error) {
+			return k8sClient.CoreV1().Pods(metasv1.NamespaceAll).Watch(options)
+		},
+	}
+
+	return cache.NewSharedIndexInformer(lw, &corev1.Pod{}, resyncPeriod, cache.Indexers{})
+}
+
+// getPod returns the given POD from the informer cache when it is synced or
+// from the API server otherwise.
+func getPod(k8sClient kubernetes.Interface, informer cache.SharedIndexInformer, pod Pod) (*corev1.Pod, error) {
+	if informer != nil && informer.HasSynced() {
+		obj, exists, err := informer.GetStore().GetByKey(pod.Namespace + "/" + pod.Name)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+		if !exists {
+			return nil, microerror.Maskf(notFoundError, "pod %s/%s", pod.Namespace, pod.Name)
+		}
+
+		p, ok := obj.(*corev1.Pod)
+		if !ok {
+			return nil, microerror.Maskf(wrongTypeError, "expected %T, got %T", p, obj)
+		}
+
+		return p, nil
+	}
+
+	p, err := k8sClient.CoreV1().Pods(pod.Namespace).Get(pod.Name, metasv1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil, microerror.Maskf(notFoundError, "pod %s/%s", pod.Namespace, pod.Name)
+	} else if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	return p, nil
+}
+
+// podFromObject returns the POD of the given object received by an informer
+// event handler, which may be a tombstone for deleted objects.
+func podFromObject(obj interface{}) (Pod, bool) {
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+
+	p, ok := obj.(*corev1.Pod)
+	if !ok {
+		return Pod{}, false
+	}
+
+	return Pod{Name: p.GetName(), Namespace: p.GetNamespace()}, true
+}
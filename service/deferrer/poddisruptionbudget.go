@@ -0,0 +1,289 @@
+package deferrer
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/giantswarm/microerror"
+	"github.com/giantswarm/micrologger"
+	corev1 "k8s.io/api/core/v1"
+	policyv1beta1 "k8s.io/api/policy/v1beta1"
+	metasv1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+)
+
+const (
+	// ProviderPodDisruptionBudget is the name of the
+	// PodDisruptionBudgetProvider.
+	ProviderPodDisruptionBudget = "poddisruptionbudget"
+)
+
+type PodDisruptionBudgetProviderConfig struct {
+	K8sClient kubernetes.Interface
+	Logger    micrologger.Logger
+
+	// NodeName is the name of the node the POD is running on.
+	NodeName string
+}
+
+// PodDisruptionBudgetProvider defers node termination as long as evicting the
+// pods remaining on the node the POD is running on would violate a
+// PodDisruptionBudget, that is while any PodDisruptionBudget selecting one of
+// these pods does not allow further disruptions.
+type PodDisruptionBudgetProvider struct {
+	k8sClient kubernetes.Interface
+	logger    micrologger.Logger
+
+	nodeName string
+
+	changed     chan struct{}
+	pdbInformer cache.SharedIndexInformer
+	podInformer cache.SharedIndexInformer
+	mutex       sync.RWMutex
+}
+
+func NewPodDisruptionBudgetProvider(config PodDisruptionBudgetProviderConfig) (*PodDisruptionBudgetProvider, error) {
+	if config.K8sClient == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.K8sClient must not be empty", config)
+	}
+	if config.Logger == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Logger must not be empty", config)
+	}
+	if config.NodeName == "" {
+		return nil, microerror.Maskf(invalidConfigError, "%T.NodeName must not be empty", config)
+	}
+
+	p := &PodDisruptionBudgetProvider{
+		k8sClient: config.K8sClient,
+		logger:    config.Logger,
+
+		nodeName: config.NodeName,
+
+		changed: make(chan struct{}),
+	}
+
+	return p, nil
+}
+
+// Boot starts informers watching the pods scheduled to the node the POD is
+// running on and all PodDisruptionBudgets. The informers are stopped once the
+// given context is done.
+func (p *PodDisruptionBudgetProvider) Boot(ctx context.Context, pod Pod) error {
+	nodeName := p.nodeName
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if p.pdbInformer != nil {
+		return nil
+	}
+
+	handler := cache.ResourceEventHandlerFuncs{
+		AddFunc:    func(obj interface{}) { p.notify() },
+		UpdateFunc: func(oldObj, newObj interface{}) { p.notify() },
+		DeleteFunc: func(obj interface{}) { p.notify() },
+	}
+
+	{
+		lw := &cache.ListWatch{
+			ListFunc: func(options metasv1.ListOptions) (runtime.Object, error) {
+				return p.k8sClient.PolicyV1beta1().PodDisruptionBudgets(metasv1.NamespaceAll).List(options)
+			},
+			WatchFunc: func(options metasv1.ListOptions) (watch.Interface, error) {
+				return p.k8sClient.PolicyV1beta1().PodDisruptionBudgets(metasv1.NamespaceAll).Watch(options)
+			},
+		}
+
+		p.pdbInformer = cache.NewSharedIndexInformer(lw, &policyv1beta1.PodDisruptionBudget{}, resyncPeriod, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+		p.pdbInformer.AddEventHandler(handler)
+	}
+
+	{
+		p.podInformer = newNodePodInformer(p.k8sClient, nodeName)
+		p.podInformer.AddEventHandler(handler)
+	}
+
+	go p.pdbInformer.Run(ctx.Done())
+	go p.podInformer.Run(ctx.Done())
+
+	return nil
+}
+
+// Changes returns a channel which is closed on the next pod or
+// PodDisruptionBudget event observed by the informers.
+func (p *PodDisruptionBudgetProvider) Changes() <-chan struct{} {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+
+	return p.changed
+}
+
+// Decide checks the PodDisruptionBudgets selecting the evictable pods remaining
+// on the node the given POD is running on. Node termination is deferred while
+// any of them has no disruptions allowed.
+func (p *PodDisruptionBudgetProvider) Decide(ctx context.Context, pod Pod) (Decision, error) {
+	nodeName := p.nodeName
+
+	p.mutex.RLock()
+	pdbInformer := p.pdbInformer
+	podInformer := p.podInformer
+	p.mutex.RUnlock()
+
+	var remaining []corev1.Pod
+	{
+		pods, err := getNodePods(p.k8sClient, podInformer, nodeName)
+		if err != nil {
+			return Decision{}, microerror.Mask(err)
+		}
+
+		remaining = evictablePods(pods, nodeName, pod)
+	}
+
+	// PodDisruptionBudgets are namespaced and only select pods in their own
+	// namespace, so they are looked up once per namespace.
+	pdbs := map[string][]policyv1beta1.PodDisruptionBudget{}
+
+	for _, r := range remaining {
+		namespace := r.GetNamespace()
+
+		list, ok := pdbs[namespace]
+		if !ok {
+			var err error
+			list, err = p.getPodDisruptionBudgets(pdbInformer, namespace)
+			if err != nil {
+				return Decision{}, microerror.Mask(err)
+			}
+
+			pdbs[namespace] = list
+		}
+
+		for _, pdb := range list {
+			selected, err := selectsPod(pdb, r)
+			if err != nil {
+				return Decision{}, microerror.Mask(err)
+			}
+			if !selected || pdb.Status.PodDisruptionsAllowed > 0 {
+				continue
+			}
+
+			_ = p.logger.LogCtx(ctx, "level", "debug", "message", "found node termination should be deferred")
+			_ = p.logger.LogCtx(ctx, "level", "debug", "message", fmt.Sprintf("poddisruptionbudget %s/%s does not allow disrupting pod %s/%s", pdb.GetNamespace(), pdb.GetName(), namespace, r.GetName()))
+
+			d := Decision{
+				Defer:  true,
+				Reason: ReasonDisruptionNotAllowed,
+			}
+
+			return d, nil
+		}
+	}
+
+	_ = p.logger.LogCtx(ctx, "level", "debug", "message", "found node termination does not have to be deferred")
+	_ = p.logger.LogCtx(ctx, "level", "debug", "message", fmt.Sprintf("poddisruptionbudgets allow disrupting the %d evictable pods remaining on node %s", len(remaining), nodeName))
+
+	d := Decision{
+		Defer:  false,
+		Reason: ReasonDisruptionAllowed,
+	}
+
+	return d, nil
+}
+
+func (p *PodDisruptionBudgetProvider) Name() string {
+	return ProviderPodDisruptionBudget
+}
+
+// Permissions returns the permissions required to look up and watch the pods
+// scheduled to the node and all PodDisruptionBudgets.
+func (p *PodDisruptionBudgetProvider) Permissions(pod Pod) []Permission {
+	var permissions []Permission
+	permissions = append(permissions, newPermissions(policyv1beta1.SchemeGroupVersion.Group, "poddisruptionbudgets", metasv1.NamespaceAll, "list", "watch")...)
+	permissions = append(permissions, newPermissions("", "pods", metasv1.NamespaceAll, "list", "watch")...)
+
+	return permissions
+}
+
+// Ready returns a notReadyError until the informer caches are synced.
+func (p *PodDisruptionBudgetProvider) Ready(ctx context.Context) error {
+	p.mutex.RLock()
+	pdbInformer := p.pdbInformer
+	podInformer := p.podInformer
+	p.mutex.RUnlock()
+
+	if pdbInformer == nil || podInformer == nil {
+		return microerror.Maskf(notReadyError, "poddisruptionbudget and pod informers not booted")
+	}
+	if !pdbInformer.HasSynced() || !podInformer.HasSynced() {
+		return microerror.Maskf(notReadyError, "poddisruptionbudget and pod informer caches not synced")
+	}
+
+	return nil
+}
+
+// notify wakes up all goroutines waiting for pod or PodDisruptionBudget
+// changes.
+func (p *PodDisruptionBudgetProvider) notify() {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	close(p.changed)
+	p.changed = make(chan struct{})
+}
+
+// getPodDisruptionBudgets returns the PodDisruptionBudgets in the given
+// namespace from the informer cache when it is synced or from the API server
+// otherwise.
+func (p *PodDisruptionBudgetProvider) getPodDisruptionBudgets(informer cache.SharedIndexInformer, namespace string) ([]policyv1beta1.PodDisruptionBudget, error) {
+	if informer != nil && informer.HasSynced() {
+		objs, err := informer.GetIndexer().ByIndex(cache.NamespaceIndex, namespace)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+
+		var pdbs []policyv1beta1.PodDisruptionBudget
+		for _, obj := range objs {
+			pdb, ok := obj.(*policyv1beta1.PodDisruptionBudget)
+			if !ok {
+				return nil, microerror.Maskf(wrongTypeError, "expected %T, got %T", pdb, obj)
+			}
+
+			pdbs = append(pdbs, *pdb.DeepCopy())
+		}
+
+		return pdbs, nil
+	}
+
+	list, err := p.k8sClient.PolicyV1beta1().PodDisruptionBudgets(namespace).List(metasv1.ListOptions{})
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	return list.Items, nil
+}
+
+// selectsPod returns true if the given PodDisruptionBudget selects the given
+// pod. Like the eviction API, a PodDisruptionBudget with an empty or missing
+// selector does not select any pod.
+func selectsPod(pdb policyv1beta1.PodDisruptionBudget, pod corev1.Pod) (bool, error) {
+	if pdb.GetNamespace() != pod.GetNamespace() {
+		return false, nil
+	}
+	if pdb.Spec.Selector == nil {
+		return false, nil
+	}
+
+	selector, err := metasv1.LabelSelectorAsSelector(pdb.Spec.Selector)
+	if err != nil {
+		return false, microerror.Mask(err)
+	}
+	if selector.Empty() {
+		return false, nil
+	}
+
+	return selector.Matches(labels.Set(pod.GetLabels())), nil
+}
@@ -0,0 +1,139 @@
+package deferrer
+
+import (
+	"context"
+	"testing"
+
+	"github.com/giantswarm/micrologger/microloggertest"
+	corev1 "k8s.io/api/core/v1"
+	policyv1beta1 "k8s.io/api/policy/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func Test_PodDisruptionBudgetProvider_Decide(t *testing.T) {
+	newPod := func(name string, app string) *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: "bar",
+				Labels: map[string]string{
+					"app": app,
+				},
+			},
+			Spec: corev1.PodSpec{
+				NodeName: "node0",
+			},
+			Status: corev1.PodStatus{
+				Phase: corev1.PodRunning,
+			},
+		}
+	}
+	newPDB := func(namespace string, app string, disruptionsAllowed int32) *policyv1beta1.PodDisruptionBudget {
+		return &policyv1beta1.PodDisruptionBudget{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      app,
+				Namespace: namespace,
+			},
+			Spec: policyv1beta1.PodDisruptionBudgetSpec{
+				Selector: &metav1.LabelSelector{
+					MatchLabels: map[string]string{
+						"app": app,
+					},
+				},
+			},
+			Status: policyv1beta1.PodDisruptionBudgetStatus{
+				PodDisruptionsAllowed: disruptionsAllowed,
+			},
+		}
+	}
+
+	testCases := []struct {
+		name                string
+		objects             []runtime.Object
+		expectedShouldDefer bool
+		expectedReason      string
+	}{
+		{
+			name: "case 0: should not defer without poddisruptionbudgets",
+			objects: []runtime.Object{
+				newPod("foo", "shutdown-deferrer"),
+				newPod("etcd-0", "etcd"),
+			},
+			expectedShouldDefer: false,
+			expectedReason:      ReasonDisruptionAllowed,
+		},
+		{
+			name: "case 1: should defer with poddisruptionbudget not allowing disruptions of remaining pod",
+			objects: []runtime.Object{
+				newPod("foo", "shutdown-deferrer"),
+				newPod("etcd-0", "etcd"),
+				newPDB("bar", "etcd", 0),
+			},
+			expectedShouldDefer: true,
+			expectedReason:      ReasonDisruptionNotAllowed,
+		},
+		{
+			name: "case 2: should not defer with poddisruptionbudget allowing disruptions of remaining pod",
+			objects: []runtime.Object{
+				newPod("foo", "shutdown-deferrer"),
+				newPod("etcd-0", "etcd"),
+				newPDB("bar", "etcd", 1),
+			},
+			expectedShouldDefer: false,
+			expectedReason:      ReasonDisruptionAllowed,
+		},
+		{
+			name: "case 3: should not defer with poddisruptionbudget only selecting the pod itself",
+			objects: []runtime.Object{
+				newPod("foo", "shutdown-deferrer"),
+				newPDB("bar", "shutdown-deferrer", 0),
+			},
+			expectedShouldDefer: false,
+			expectedReason:      ReasonDisruptionAllowed,
+		},
+		{
+			name: "case 4: should not defer with poddisruptionbudget in another namespace",
+			objects: []runtime.Object{
+				newPod("foo", "shutdown-deferrer"),
+				newPod("etcd-0", "etcd"),
+				newPDB("baz", "etcd", 0),
+			},
+			expectedShouldDefer: false,
+			expectedReason:      ReasonDisruptionAllowed,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var p *PodDisruptionBudgetProvider
+			{
+				c := PodDisruptionBudgetProviderConfig{
+					K8sClient: fake.NewSimpleClientset(tc.objects...),
+					Logger:    microloggertest.New(),
+
+					NodeName: "node0",
+				}
+
+				var err error
+				p, err = NewPodDisruptionBudgetProvider(c)
+				if err != nil {
+					t.Fatalf("error == %#v, want nil", err)
+				}
+			}
+
+			d, err := p.Decide(context.Background(), Pod{Name: "foo", Namespace: "bar"})
+			if err != nil {
+				t.Fatalf("error == %#v, want nil", err)
+			}
+
+			if d.Defer != tc.expectedShouldDefer {
+				t.Fatalf("d.Defer == %v, want %v", d.Defer, tc.expectedShouldDefer)
+			}
+			if d.Reason != tc.expectedReason {
+				t.Fatalf("d.Reason == %q, want %q", d.Reason, tc.expectedReason)
+			}
+		})
+	}
+}
@@ -0,0 +1,135 @@
+package deferrer
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+const (
+	// CombinationAll defers node termination as long as any provider defers it,
+	// i.e. all providers must allow node termination.
+	CombinationAll = "all"
+	// CombinationAny defers node termination as long as all providers defer
+	// it, i.e. any provider may allow node termination.
+	CombinationAny = "any"
+)
+
+// Pod identifies the POD node termination is deferred for.
+type Pod struct {
+	Name      string
+	Namespace string
+}
+
+// Provider decides whether node termination has to be deferred based on a
+// single signal, e.g. the DrainerConfig of the POD.
+type Provider interface {
+	// Boot starts background work of the provider like informers for the
+	// given POD. The POD is empty in multi-tenant mode, in which case the
+	// background work has to cover all PODs. It must not block and stops all
+	// work once the given context is done.
+	Boot(ctx context.Context, pod Pod) error
+	// Changes returns a channel which is closed on the next change that may
+	// alter the decision of the provider. Providers not able to observe
+	// changes return nil.
+	Changes() <-chan struct{}
+	// Decide returns the decision of the provider for the given POD. An error
+	// is returned when the signal could not be looked up, in which case the
+	// configured failure policy is applied.
+	Decide(ctx context.Context, pod Pod) (Decision, error)
+	// Name identifies the provider in decisions and logs.
+	Name() string
+}
+
+// ReadinessChecker is implemented by providers able to tell whether they are
+// ready to decide, e.g. whether their informer caches are synced.
+type ReadinessChecker interface {
+	// Ready returns a notReadyError describing why the provider is not ready
+	// yet. It returns nil when the provider is ready.
+	Ready(ctx context.Context) error
+}
+
+// PermissionRequirer is implemented by providers which access the Kubernetes
+// API and are able to list the permissions they require for the given POD.
+type PermissionRequirer interface {
+	Permissions(pod Pod) []Permission
+}
+
+// Permission is a Kubernetes API permission required by a provider. An empty
+// Namespace means all namespaces, or the resource is not namespaced.
+type Permission struct {
+	Group     string
+	Namespace string
+	Resource  string
+	Verb      string
+}
+
+// String returns a human readable representation of the permission like
+// "get drainerconfigs.core.giantswarm.io in namespace default".
+func (p Permission) String() string {
+	resource := p.Resource
+	if p.Group != "" {
+		resource = fmt.Sprintf("%s.%s", p.Resource, p.Group)
+	}
+
+	if p.Namespace == "" {
+		return fmt.Sprintf("%s %s", p.Verb, resource)
+	}
+
+	return fmt.Sprintf("%s %s in namespace %s", p.Verb, resource, p.Namespace)
+}
+
+// newPermissions returns one permission for each of the given verbs.
+func newPermissions(group, resource, namespace string, verbs ...string) []Permission {
+	var permissions []Permission
+	for _, v := range verbs {
+		permissions = append(permissions, Permission{
+			Group:     group,
+			Namespace: namespace,
+			Resource:  resource,
+			Verb:      v,
+		})
+	}
+
+	return permissions
+}
+
+// combine returns the decision of the first provider determining the combined
+// decision according to the given combination.
+func combine(combination string, decisions []Decision) Decision {
+	determining := combination == CombinationAll
+
+	for _, d := range decisions {
+		if d.Defer == determining {
+			return d
+		}
+	}
+
+	return decisions[0]
+}
+
+// anyClosed returns a channel which is closed once any of the given channels
+// is closed. All goroutines involved are stopped once the returned cancel
+// function is called or the given context is done.
+func anyClosed(ctx context.Context, channels []<-chan struct{}) (<-chan struct{}, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(ctx)
+
+	closed := make(chan struct{})
+	var once sync.Once
+
+	for _, ch := range channels {
+		if ch == nil {
+			continue
+		}
+
+		go func(ch <-chan struct{}) {
+			select {
+			case <-ch:
+				once.Do(func() { close(closed) })
+			case <-ctx.Done():
+			}
+		}(ch)
+	}
+
+	return closed, cancel
+}
@@ -3,130 +3,933 @@ package deferrer
 import (
 	"context"
 	"fmt"
-	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
 
-	"github.com/giantswarm/apiextensions/pkg/apis/core/v1alpha1"
-	"github.com/giantswarm/apiextensions/pkg/clientset/versioned"
 	"github.com/giantswarm/microerror"
 	"github.com/giantswarm/micrologger"
-	apierrors "k8s.io/apimachinery/pkg/api/errors"
-	metasv1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
 )
 
 const (
-	EnvKeyMyPodName      = "MY_POD_NAME"
-	EnvKeyMyPodNamespace = "MY_POD_NAMESPACE"
+	// AnnotationForce is the POD or DrainerConfig annotation forcing node
+	// termination to be allowed or deferred regardless of the providers and
+	// the deadline. Its value is one of ForceAllow or ForceDefer. The POD
+	// annotation takes precedence over the DrainerConfig annotation.
+	AnnotationForce = "shutdown-deferrer.giantswarm.io/force"
+	// AnnotationMaxDefer is the POD or DrainerConfig annotation overriding the
+	// configured deadline after which node termination is not deferred
+	// anymore. Its value is a duration like 10m. The POD annotation takes
+	// precedence over the DrainerConfig annotation.
+	AnnotationMaxDefer = "shutdown-deferrer.giantswarm.io/max-defer"
+)
+
+const (
+	// FailurePolicyAllow allows node termination when a provider fails to look
+	// up its signal.
+	FailurePolicyAllow = "allow"
+	// FailurePolicyAllowAfterFailures defers node termination when a provider
+	// fails to look up its signal, until lookups failed FailureThreshold times
+	// in a row. Node termination is allowed from then on until lookups succeed
+	// again.
+	FailurePolicyAllowAfterFailures = "allow-after-failures"
+	// FailurePolicyDefer defers node termination when a provider fails to look
+	// up its signal.
+	FailurePolicyDefer = "defer"
+)
+
+const (
+	// failureRetryInterval is the interval in which Wait re-evaluates the
+	// decision while lookups fail, because no change events can be expected in
+	// that case. Failures of a POD are counted at most once per interval, so
+	// that the FailureThreshold does not depend on the number of callers.
+	failureRetryInterval = 5 * time.Second
 )
 
 type Config struct {
-	G8sClient versioned.Interface
+	// EventRecorder records Kubernetes Events when node termination is first
+	// deferred, when it is not deferred anymore and when lookups start
+	// failing. Events are not recorded when it is nil.
+	EventRecorder EventRecorder
+	// K8sClient is used to check that PODs given to DecideFor exist in
+	// multi-tenant mode until PodInformer is synced. It must be given when
+	// MultiTenant is true.
+	K8sClient kubernetes.Interface
 	Logger    micrologger.Logger
+	// MultiTenant makes the service decide for arbitrary PODs given to
+	// DecideFor instead of for the POD it is running in. Providers are booted
+	// for all PODs then.
+	MultiTenant bool
+	// Overrides persists overrides set using SetOverride, which take
+	// precedence over the decisions of the providers until they expire.
+	// Overrides are not supported when it is nil.
+	Overrides OverrideStore
+	// Pod is the POD the service is running in. Its name and namespace must
+	// be given unless MultiTenant is true. See the identity package for
+	// resolving them at startup.
+	Pod Pod
+	// PodInformer caches the PODs decided for in multi-tenant mode, see
+	// NewPodInformer. It is run by Boot and the state kept for a POD is
+	// dropped once the POD is deleted. It must be given when MultiTenant is
+	// true.
+	PodInformer cache.SharedIndexInformer
+	// PodAnnotations provides the AnnotationForce and AnnotationMaxDefer
+	// annotations of the POD. POD annotations are ignored when it is nil.
+	PodAnnotations PodAnnotationSource
+	// Providers are asked for their decisions in the given order. At least one
+	// provider must be given.
+	Providers []Provider
+
+	// Combination is one of the Combination* constants and defines how the
+	// decisions of the providers are combined. Defaults to CombinationAll.
+	Combination string
+	// Deadline is the maximum time node termination is deferred, measured from
+	// the first decision deferring it since providers last allowed it. Zero
+	// disables the deadline. It can be
+	// overridden per POD or DrainerConfig using the AnnotationMaxDefer
+	// annotation.
+	Deadline time.Duration
+	// FailurePolicy is one of the FailurePolicy* constants and defines the
+	// decision made when a provider fails to look up its signal. Defaults to
+	// FailurePolicyDefer.
+	FailurePolicy string
+	// FailureThreshold is the number of consecutive lookup failures after which
+	// node termination is allowed when FailurePolicy is
+	// FailurePolicyAllowAfterFailures. Failures are counted at most once every
+	// 5 seconds, regardless of the number of callers.
+	FailureThreshold int
 }
 
 type Service struct {
-	g8sClient versioned.Interface
-	logger    micrologger.Logger
+	eventRecorder  EventRecorder
+	k8sClient      kubernetes.Interface
+	logger         micrologger.Logger
+	multiTenant    bool
+	overrides      OverrideStore
+	pod            Pod
+	podAnnotations PodAnnotationSource
+	podInformer    cache.SharedIndexInformer
+	providers      []Provider
+
+	allowed             chan struct{}
+	bootErrs            map[string]error
+	lastAllowed         bool
+	consecutiveFailures map[Pod]int
+	lastFailure         map[Pod]time.Time
+	deferredRecorded    map[Pod]bool
+	deferredSince       map[Pod]time.Time
+	mutex               sync.Mutex
+
+	combination      string
+	deadline         time.Duration
+	failurePolicy    string
+	failureThreshold int
+	retryInterval    time.Duration
 }
 
 func New(config Config) (*Service, error) {
-	if config.G8sClient == nil {
-		return nil, microerror.Maskf(invalidConfigError, "%T.G8sClient must not be empty", config)
-	}
 	if config.Logger == nil {
 		return nil, microerror.Maskf(invalidConfigError, "%T.Logger must not be empty", config)
 	}
+	if config.MultiTenant && config.K8sClient == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.K8sClient must not be empty in multi-tenant mode", config)
+	}
+	if config.MultiTenant && config.PodInformer == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.PodInformer must not be empty in multi-tenant mode", config)
+	}
+	if !config.MultiTenant && config.Pod.Name == "" {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Pod.Name must not be empty", config)
+	}
+	if !config.MultiTenant && config.Pod.Namespace == "" {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Pod.Namespace must not be empty", config)
+	}
+	if len(config.Providers) == 0 {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Providers must not be empty", config)
+	}
+
+	if config.Combination == "" {
+		config.Combination = CombinationAll
+	}
+	if config.Combination != CombinationAll && config.Combination != CombinationAny {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Combination must be one of %q or %q", config, CombinationAll, CombinationAny)
+	}
+	if config.Deadline < 0 {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Deadline must not be negative", config)
+	}
+	if config.FailurePolicy == "" {
+		config.FailurePolicy = FailurePolicyDefer
+	}
+	switch config.FailurePolicy {
+	case FailurePolicyAllow, FailurePolicyDefer:
+	case FailurePolicyAllowAfterFailures:
+		if config.FailureThreshold <= 0 {
+			return nil, microerror.Maskf(invalidConfigError, "%T.FailureThreshold must be greater than 0 for failure policy %q", config, config.FailurePolicy)
+		}
+	default:
+		return nil, microerror.Maskf(invalidConfigError, "%T.FailurePolicy must be one of %q, %q or %q", config, FailurePolicyAllow, FailurePolicyAllowAfterFailures, FailurePolicyDefer)
+	}
 
 	s := &Service{
-		g8sClient: config.G8sClient,
-		logger:    config.Logger,
+		eventRecorder:  config.EventRecorder,
+		k8sClient:      config.K8sClient,
+		logger:         config.Logger,
+		multiTenant:    config.MultiTenant,
+		overrides:      config.Overrides,
+		pod:            config.Pod,
+		podAnnotations: config.PodAnnotations,
+		podInformer:    config.PodInformer,
+		providers:      config.Providers,
+
+		allowed:             make(chan struct{}),
+		consecutiveFailures: map[Pod]int{},
+		lastFailure:         map[Pod]time.Time{},
+		deferredRecorded:    map[Pod]bool{},
+		deferredSince:       map[Pod]time.Time{},
+
+		combination:      config.Combination,
+		deadline:         config.Deadline,
+		failurePolicy:    config.FailurePolicy,
+		failureThreshold: config.FailureThreshold,
+		retryInterval:    failureRetryInterval,
+	}
+
+	if s.multiTenant {
+		s.podInformer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+			DeleteFunc: func(obj interface{}) {
+				pod, ok := podFromObject(obj)
+				if ok {
+					s.forget(pod)
+				}
+			},
+		})
 	}
 
 	return s, nil
 }
 
-// ShouldDefer finds corresponding DrainerConfig for the POD it's running in
-// and checks if node in current POD is drained yet. If DrainerConfig doesn't
-// exist or doesn't have Drained or Timeout condition, node termination should
-// be deferred.
-//
-// Current POD name and namespace are picked from environment variables with
-// corresponding keys defined in constants EnvKeyMyPodName &
-// EnvKeyMyPodNamespace. Defining these env variables is most conveniently
-// achieved by utilizing Kubernetes Downward API:
-// https://kubernetes.io/docs/tasks/inject-data-application/environment-variable-expose-pod-information/
-func (s *Service) ShouldDefer(ctx context.Context) (bool, error) {
-	var err error
+// Boot boots all providers, the override store and the POD annotation source
+// for the POD the service is running in, or for all PODs in multi-tenant mode.
+// They stop their background work once the given context is done.
+func (s *Service) Boot(ctx context.Context) error {
+	// All components are booted even when one fails, so that a single failure
+	// does not leave the caches of the others unsynced. Failures are reported
+	// by Ready.
+	bootErrs := map[string]error{}
+	for _, p := range s.providers {
+		err := p.Boot(ctx, s.pod)
+		if err != nil {
+			bootErrs[fmt.Sprintf("provider %s", p.Name())] = err
+		}
+	}
 
-	var podName, podNamespace string
-	{
-		_ = s.logger.LogCtx(ctx, "level", "debug", "message", "finding pod name and namespace")
+	if s.overrides != nil {
+		err := s.overrides.Boot(ctx, s.pod)
+		if err != nil {
+			bootErrs["override store"] = err
+		}
+	}
 
-		podName, err = s.getPodName()
+	if s.podAnnotations != nil {
+		err := s.podAnnotations.Boot(ctx, s.pod)
 		if err != nil {
-			return false, microerror.Mask(err)
+			bootErrs["pod annotation source"] = err
 		}
-		podNamespace, err = s.getPodNamespace()
+	}
+
+	if s.multiTenant {
+		go s.podInformer.Run(ctx.Done())
+	}
+
+	s.mutex.Lock()
+	s.bootErrs = bootErrs
+	s.mutex.Unlock()
+
+	if len(bootErrs) != 0 {
+		return microerror.Maskf(bootFailedError, "%s", formatBootErrs(bootErrs))
+	}
+
+	return nil
+}
+
+// Allowed returns a channel which is closed once the next decision allowing
+// node termination of the POD the service is running in has been made. It is
+// never closed in multi-tenant mode.
+func (s *Service) Allowed() <-chan struct{} {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.allowed
+}
+
+// MultiTenant returns true when the service decides for arbitrary PODs given to
+// DecideFor.
+func (s *Service) MultiTenant() bool {
+	return s.multiTenant
+}
+
+// LastAllowed returns true when the last decision for the POD the service is
+// running in allowed node termination. It is always false in multi-tenant mode
+// and before the first decision.
+func (s *Service) LastAllowed() bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.lastAllowed
+}
+
+// Permissions returns the Kubernetes API permissions required by the
+// providers, the override store, the POD annotation source and the event
+// recorder for the POD the service is running in, or for all PODs in
+// multi-tenant mode. In multi-tenant mode getting PODs is required as well.
+func (s *Service) Permissions() []Permission {
+	var permissions []Permission
+	for _, p := range s.providers {
+		r, ok := p.(PermissionRequirer)
+		if !ok {
+			continue
+		}
+
+		permissions = append(permissions, r.Permissions(s.pod)...)
+	}
+
+	r, ok := s.overrides.(PermissionRequirer)
+	if ok {
+		permissions = append(permissions, r.Permissions(s.pod)...)
+	}
+
+	r, ok = s.podAnnotations.(PermissionRequirer)
+	if ok {
+		permissions = append(permissions, r.Permissions(s.pod)...)
+	}
+
+	r, ok = s.eventRecorder.(PermissionRequirer)
+	if ok {
+		permissions = append(permissions, r.Permissions(s.pod)...)
+	}
+
+	if s.multiTenant {
+		permissions = append(permissions, newPermissions("", "pods", "", "get", "list", "watch")...)
+	}
+
+	return permissions
+}
+
+// Ready returns a notReadyError when any component failed to boot or any
+// provider implementing ReadinessChecker is not ready to decide yet.
+func (s *Service) Ready(ctx context.Context) error {
+	s.mutex.Lock()
+	bootErrs := s.bootErrs
+	s.mutex.Unlock()
+
+	if len(bootErrs) != 0 {
+		return microerror.Maskf(notReadyError, "failed to boot: %s", formatBootErrs(bootErrs))
+	}
+
+	if s.multiTenant && !s.podInformer.HasSynced() {
+		return microerror.Maskf(notReadyError, "pod cache is not synced")
+	}
+
+	for _, p := range s.providers {
+		c, ok := p.(ReadinessChecker)
+		if !ok {
+			continue
+		}
+
+		err := c.Ready(ctx)
 		if err != nil {
-			return false, microerror.Mask(err)
+			return microerror.Maskf(notReadyError, "provider %s is not ready: %s", p.Name(), err)
 		}
+	}
 
-		_ = s.logger.LogCtx(ctx, "level", "debug", "message", fmt.Sprintf("found pod name and namespace: %s, %s", podName, podNamespace))
+	return nil
+}
+
+// ShouldDefer asks all providers whether node termination of the POD it's
+// running in has to be deferred and combines their decisions according to the
+// configured combination. See DrainerConfigProvider for the DrainerConfig
+// based decision.
+func (s *Service) ShouldDefer(ctx context.Context) (bool, error) {
+	decision, err := s.Decide(ctx)
+	if err != nil {
+		return decision.Defer, microerror.Mask(err)
 	}
 
-	var drainerConfig *v1alpha1.DrainerConfig
-	{
-		_ = s.logger.LogCtx(ctx, "level", "debug", "message", "finding drainerconfig for pod")
+	return decision.Defer, nil
+}
+
+// Decide works like ShouldDefer but returns the full Decision, which
+// additionally explains why node termination has to be deferred or not. In
+// case a provider fails to look up its signal, the configured failure policy
+// is applied and the provider's Decision has the reason ReasonAPIError.
+//
+// In multi-tenant mode there is no POD to decide for and DecideFor has to be
+// used instead.
+func (s *Service) Decide(ctx context.Context) (Decision, error) {
+	if s.multiTenant {
+		return Decision{}, microerror.Maskf(invalidConfigError, "pod must be given in multi-tenant mode")
+	}
+
+	decision, err := s.DecideFor(ctx, s.pod)
+	if err != nil {
+		return Decision{}, microerror.Mask(err)
+	}
+
+	return decision, nil
+}
 
-		drainerConfig, err = s.g8sClient.CoreV1alpha1().DrainerConfigs(podNamespace).Get(podName, metasv1.GetOptions{})
-		if apierrors.IsNotFound(err) {
-			_ = s.logger.LogCtx(ctx, "level", "debug", "message", "did not find drainerconfig")
-			drainerConfig = nil
+// DecideFor works like Decide but decides for the given POD. It is meant to be
+// used in multi-tenant mode, in which the service serves decisions for many
+// PODs. In multi-tenant mode a notFoundError is returned when the POD does not
+// exist, so that no state is kept and no Events are recorded for it.
+func (s *Service) DecideFor(ctx context.Context, pod Pod) (Decision, error) {
+	if pod.Name == "" || pod.Namespace == "" {
+		return Decision{}, microerror.Maskf(invalidRequestError, "pod name and namespace must not be empty")
+	}
+
+	if s.multiTenant {
+		_, err := getPod(s.k8sClient, s.podInformer, pod)
+		if IsNotFound(err) {
+			s.forget(pod)
+			return Decision{}, microerror.Mask(err)
 		} else if err != nil {
-			return true, microerror.Mask(err)
+			return Decision{}, microerror.Mask(err)
 		}
+	}
 
-		_ = s.logger.LogCtx(ctx, "level", "debug", "message", "found drainerconfig for pod")
+	decision, err := s.decide(ctx, pod)
+	if err != nil {
+		return Decision{}, microerror.Mask(err)
 	}
 
+	// Decisions for a DrainerConfig target given by the caller do not
+	// describe the POD, so they are neither tracked nor recorded.
+	if !isStateless(ctx) {
+		s.trackDecision(pod, decision)
+		s.recordTransition(ctx, pod, decision)
+	}
+
+	return decision, nil
+}
+
+func (s *Service) decide(ctx context.Context, pod Pod) (Decision, error) {
+	stateless := isStateless(ctx)
+
+	{
+		override := s.activeOverride(ctx, pod)
+		if override != nil {
+			_ = s.logger.LogCtx(ctx, "level", "debug", "message", fmt.Sprintf("found override forcing node termination to %s until %s", override.Force, override.Expires.Format(time.RFC3339)))
+
+			decision := Decision{
+				Defer:    override.Force == ForceDefer,
+				Reason:   ReasonOverride,
+				Provider: ProviderOverride,
+				Override: override,
+			}
+
+			if !decision.Defer && !stateless {
+				s.forgetDeferred(pod)
+			}
+
+			return decision, nil
+		}
+	}
+
+	podAnnotations := s.lookupPodAnnotations(ctx, pod)
+
+	if force := s.forceAnnotation(ctx, podAnnotations); force != "" {
+		_ = s.logger.LogCtx(ctx, "level", "debug", "message", fmt.Sprintf("found pod annotation forcing node termination to %s", force))
+
+		decision := newForcedDecision(force, ProviderOverride)
+		if !decision.Defer && !stateless {
+			s.forgetDeferred(pod)
+		}
+
+		return decision, nil
+	}
+
+	decisions := make([]Decision, len(s.providers))
+	errs := make([]error, len(s.providers))
+	var failed bool
+	{
+		for i, p := range s.providers {
+			decisions[i], errs[i] = p.Decide(ctx, pod)
+			if errs[i] != nil {
+				_ = s.logger.LogCtx(ctx, "level", "warning", "message", fmt.Sprintf("provider %s failed to decide", p.Name()), "stack", fmt.Sprintf("%#v", errs[i]))
+				failed = true
+			}
+		}
+	}
+
+	var decision Decision
 	{
-		_ = s.logger.LogCtx(ctx, "level", "debug", "message", "finding if node termination has to be defered")
+		var failures int
+		var counted bool
+		if stateless {
+			if failed {
+				failures = 1
+			}
+		} else {
+			failures, counted = s.countFailures(pod, failed)
+		}
 
-		if drainerConfig == nil {
-			_ = s.logger.LogCtx(ctx, "level", "debug", "message", "found node termination should be deferred")
-			_ = s.logger.LogCtx(ctx, "level", "debug", "message", "pod drainerconfig does not exist")
-			return true, nil
+		var maxDefer time.Duration
+		for i, p := range s.providers {
+			if errs[i] != nil {
+				decisions[i] = s.applyFailurePolicy(ctx, errs[i], failures)
+			}
+			decisions[i].Provider = p.Name()
+
+			if maxDefer == 0 {
+				maxDefer = decisions[i].MaxDefer
+			}
 		}
 
-		if drainerConfig.Status.HasDrainedCondition() {
-			_ = s.logger.LogCtx(ctx, "level", "debug", "message", "found node termination does not have to be deferred")
-			_ = s.logger.LogCtx(ctx, "level", "debug", "message", "pod drainerconfig has drained condition")
-			return false, nil
+		decision = combine(s.combination, decisions)
+
+		// A provider forcing the decision, e.g. because of the AnnotationForce
+		// annotation of the DrainerConfig, takes precedence over the
+		// combined decision and the deadline.
+		var forced bool
+		for _, d := range decisions {
+			if d.Force != "" {
+				decision = newForcedDecision(d.Force, d.Provider)
+				decision.DrainerConfig = d.DrainerConfig
+				forced = true
+				break
+			}
 		}
-		if drainerConfig.Status.HasTimeoutCondition() {
-			_ = s.logger.LogCtx(ctx, "level", "debug", "message", "found node termination does not have to be deferred")
-			_ = s.logger.LogCtx(ctx, "level", "debug", "message", "pod drainerconfig has timeout condition")
-			return false, nil
+
+		if v, ok := podAnnotations[AnnotationMaxDefer]; ok {
+			d, err := parseMaxDefer(v)
+			if err != nil {
+				_ = s.logger.LogCtx(ctx, "level", "warning", "message", fmt.Sprintf("ignoring invalid pod annotation: %s", err))
+			} else {
+				maxDefer = d
+			}
 		}
 
-		_ = s.logger.LogCtx(ctx, "level", "debug", "message", "found node termination should be deferred")
-		return true, nil
+		switch {
+		case stateless:
+			// Stateless decisions neither track nor apply the deadline.
+		case forced && decision.Defer:
+			// The deadline does not apply to forced decisions.
+		case decision.Defer:
+			decision = s.applyDeadline(ctx, pod, decision, maxDefer)
+		default:
+			s.forgetDeferred(pod)
+		}
+
+		_ = s.logger.LogCtx(ctx, "level", "debug", "message", fmt.Sprintf("found provider %s determines node termination should be deferred: %t", decision.Provider, decision.Defer))
+
+		// Only the first failure is recorded, so that Events are not
+		// flooded while lookups keep failing.
+		if counted && failures == 1 {
+			for i, p := range s.providers {
+				if errs[i] != nil {
+					s.recordEvent(ctx, pod, decision, corev1.EventTypeWarning, EventReasonLookupFailed, fmt.Sprintf("Provider %s failed to decide whether node termination has to be deferred: %s", p.Name(), errs[i]))
+				}
+			}
+		}
 	}
+
+	return decision, nil
 }
 
-func (s *Service) getPodName() (string, error) {
-	podName := os.Getenv(EnvKeyMyPodName)
-	if podName == "" {
-		return "", microerror.Maskf(invalidConfigError, "pod name not present in runtime - make sure $%s is set", EnvKeyMyPodName)
+// Wait blocks until node termination does not have to be deferred anymore or
+// the given context is done. The decision is evaluated like in Watch, so
+// callers learn about a drained node as soon as the DrainerConfig is updated.
+// It returns the last decision made. When the context is done before
+// termination is allowed, that decision defers node termination.
+func (s *Service) Wait(ctx context.Context) (Decision, error) {
+	last := Decision{Defer: true}
+	err := s.Watch(ctx, func(decision Decision) bool {
+		last = decision
+		return decision.Defer
+	})
+	if err != nil {
+		return Decision{Defer: true}, microerror.Mask(err)
 	}
 
-	return podName, nil
+	if last.Defer {
+		_ = s.logger.LogCtx(ctx, "level", "debug", "message", "stopped waiting for node termination to not be deferred")
+	}
+
+	return last, nil
 }
 
-func (s *Service) getPodNamespace() (string, error) {
-	podNamespace := os.Getenv(EnvKeyMyPodNamespace)
-	if podNamespace == "" {
-		return "", microerror.Maskf(invalidConfigError, "pod namespace not present in runtime - make sure $%s is set", EnvKeyMyPodNamespace)
+// Watch calls the given function with the decision for the POD the service is
+// running in. The decision is evaluated once initially and then again every
+// time a provider, the override store or the POD annotation source observes a
+// change, e.g. the DrainerConfig informer receives an event. Consecutive
+// decisions may therefore be equal. Watch returns once the given function
+// returns false, the given context is done or deciding fails.
+func (s *Service) Watch(ctx context.Context, fn func(Decision) bool) error {
+	for {
+		// The change channels have to be fetched before evaluating the
+		// decision. Otherwise we could miss an event happening in between.
+		changes := make([]<-chan struct{}, 0, len(s.providers)+2)
+		for _, p := range s.providers {
+			changes = append(changes, p.Changes())
+		}
+		if s.overrides != nil {
+			changes = append(changes, s.overrides.Changes())
+		}
+		if s.podAnnotations != nil {
+			changes = append(changes, s.podAnnotations.Changes())
+		}
+
+		decision, err := s.Decide(ctx)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+		if !fn(decision) {
+			return nil
+		}
+
+		// Failed lookups do not cause change events, so we have to retry on our
+		// own in order to apply the failure policy.
+		var retry <-chan time.Time
+		if decision.Reason == ReasonAPIError {
+			retry = time.After(s.retryInterval)
+		}
+		// Expiring overrides do not cause change events either.
+		if decision.Override != nil {
+			retry = time.After(time.Until(decision.Override.Expires))
+		}
+
+		changed, cancel := anyClosed(ctx, changes)
+
+		select {
+		case <-retry:
+			_ = s.logger.LogCtx(ctx, "level", "debug", "message", "retrying to decide")
+		case <-changed:
+			_ = s.logger.LogCtx(ctx, "level", "debug", "message", "observed provider change")
+		case <-ctx.Done():
+			cancel()
+			return nil
+		}
+
+		cancel()
+	}
+}
+
+// Override returns the override of the POD the service is running in, or nil
+// if there is none. Expired overrides are returned as well.
+func (s *Service) Override(ctx context.Context) (*Override, error) {
+	if s.overrides == nil {
+		return nil, microerror.Maskf(invalidConfigError, "overrides are not supported")
+	}
+	if s.multiTenant {
+		return nil, microerror.Maskf(invalidConfigError, "overrides are not supported in multi-tenant mode")
+	}
+
+	override, err := s.overrides.Get(ctx, s.pod)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	return override, nil
+}
+
+// SetOverride forces node termination of the POD the service is running in to
+// be allowed or deferred until the given override expires. The override takes
+// precedence over the providers and the deadline.
+func (s *Service) SetOverride(ctx context.Context, override Override) error {
+	if s.overrides == nil {
+		return microerror.Maskf(invalidConfigError, "overrides are not supported")
+	}
+	if s.multiTenant {
+		return microerror.Maskf(invalidConfigError, "overrides are not supported in multi-tenant mode")
+	}
+
+	err := override.Validate()
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	err = s.overrides.Set(ctx, s.pod, override)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	_ = s.logger.LogCtx(ctx, "level", "info", "message", fmt.Sprintf("set override forcing node termination to %s until %s", override.Force, override.Expires.Format(time.RFC3339)), "reason", override.Reason, "user", override.User)
+
+	return nil
+}
+
+// DeleteOverride removes the override of the POD the service is running in, so
+// that the providers decide again.
+func (s *Service) DeleteOverride(ctx context.Context) error {
+	if s.overrides == nil {
+		return microerror.Maskf(invalidConfigError, "overrides are not supported")
+	}
+	if s.multiTenant {
+		return microerror.Maskf(invalidConfigError, "overrides are not supported in multi-tenant mode")
+	}
+
+	err := s.overrides.Delete(ctx, s.pod)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	_ = s.logger.LogCtx(ctx, "level", "info", "message", "deleted override")
+
+	return nil
+}
+
+// activeOverride returns the override of the given POD unless it expired. A
+// failing lookup is logged and treated like no override, so that the
+// providers still decide.
+func (s *Service) activeOverride(ctx context.Context, pod Pod) *Override {
+	if s.overrides == nil {
+		return nil
+	}
+
+	override, err := s.overrides.Get(ctx, pod)
+	if err != nil {
+		_ = s.logger.LogCtx(ctx, "level", "warning", "message", "failed to look up override", "stack", fmt.Sprintf("%#v", err))
+		return nil
+	}
+	if override == nil || override.Expired(time.Now()) {
+		return nil
+	}
+
+	return override
+}
+
+// lookupPodAnnotations returns the annotations of the given POD. A failing
+// lookup is logged and treated like no annotations, so that the providers
+// still decide.
+func (s *Service) lookupPodAnnotations(ctx context.Context, pod Pod) map[string]string {
+	if s.podAnnotations == nil {
+		return nil
+	}
+
+	annotations, err := s.podAnnotations.Annotations(ctx, pod)
+	if err != nil {
+		_ = s.logger.LogCtx(ctx, "level", "warning", "message", "failed to look up pod annotations", "stack", fmt.Sprintf("%#v", err))
+		return nil
+	}
+
+	return annotations
+}
+
+// forceAnnotation returns the value of the AnnotationForce annotation of the
+// POD, or an empty string if it is not set or invalid.
+func (s *Service) forceAnnotation(ctx context.Context, annotations map[string]string) string {
+	v, ok := annotations[AnnotationForce]
+	if !ok {
+		return ""
+	}
+
+	force, err := parseForce(v)
+	if err != nil {
+		_ = s.logger.LogCtx(ctx, "level", "warning", "message", fmt.Sprintf("ignoring invalid pod annotation: %s", err))
+		return ""
+	}
+
+	return force
+}
+
+// applyDeadline stops deferring node termination once it has been deferred for
+// longer than the deadline. The given maxDefer, e.g. defined by the
+// AnnotationMaxDefer annotation of the DrainerConfig, takes precedence over the
+// configured deadline when it is not zero.
+func (s *Service) applyDeadline(ctx context.Context, pod Pod, decision Decision, maxDefer time.Duration) Decision {
+	deadline := s.deadline
+	if maxDefer != 0 {
+		deadline = maxDefer
+	}
+
+	deferredSince := s.markDeferred(pod)
+
+	if deadline == 0 {
+		return decision
+	}
+
+	if time.Since(deferredSince) < deadline {
+		return decision
+	}
+
+	_ = s.logger.LogCtx(ctx, "level", "debug", "message", fmt.Sprintf("found node termination does not have to be deferred because it has been deferred since %s which exceeds the deadline of %s", deferredSince.Format(time.RFC3339), deadline))
+
+	decision.Defer = false
+	decision.Reason = ReasonDeadlineExceeded
+
+	return decision
+}
+
+// applyFailurePolicy returns the Decision for a failed lookup according to the
+// configured failure policy and the given number of consecutive failures.
+func (s *Service) applyFailurePolicy(ctx context.Context, err error, failures int) Decision {
+	d := Decision{
+		Defer:  true,
+		Reason: ReasonAPIError,
+		Error:  err.Error(),
+	}
+
+	switch s.failurePolicy {
+	case FailurePolicyAllow:
+		d.Defer = false
+	case FailurePolicyAllowAfterFailures:
+		d.Defer = failures < s.failureThreshold
 	}
 
-	return podNamespace, nil
+	_ = s.logger.LogCtx(ctx, "level", "debug", "message", fmt.Sprintf("applied failure policy %q after %d consecutive failures, node termination should be deferred: %t", s.failurePolicy, failures, d.Defer))
+
+	return d
+}
+
+// countFailures increments the number of consecutive failed decisions for the
+// given POD when failed is true and resets it otherwise. Failures are counted
+// at most once per retry interval. The resulting number is returned together
+// with whether the failure has been counted by this call.
+func (s *Service) countFailures(pod Pod, failed bool) (int, bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if !failed {
+		delete(s.consecutiveFailures, pod)
+		delete(s.lastFailure, pod)
+		return 0, false
+	}
+
+	// Every caller evaluating the decision, e.g. every watcher, observes the
+	// same failed lookups, so they are only counted once per retry interval.
+	last, ok := s.lastFailure[pod]
+	if ok && time.Since(last) < s.retryInterval {
+		return s.consecutiveFailures[pod], false
+	}
+
+	s.consecutiveFailures[pod]++
+	s.lastFailure[pod] = time.Now()
+
+	return s.consecutiveFailures[pod], true
+}
+
+// trackDecision updates the decision metrics. The deferred gauge only tracks
+// the POD the service is running in, so it is not updated in multi-tenant
+// mode.
+func (s *Service) trackDecision(pod Pod, decision Decision) {
+	decisionCounter.WithLabelValues(strconv.FormatBool(decision.Defer), decision.Reason).Inc()
+
+	if s.multiTenant || pod != s.pod {
+		return
+	}
+
+	if !decision.Defer {
+		deferredGauge.Set(0)
+		s.notifyAllowed()
+		return
+	}
+
+	s.mutex.Lock()
+	s.lastAllowed = false
+	s.mutex.Unlock()
+
+	deferredGauge.Set(time.Since(s.markDeferred(pod)).Seconds())
+}
+
+// recordTransition records an Event when node termination of the given POD is
+// deferred for the first time and when it is not deferred anymore.
+func (s *Service) recordTransition(ctx context.Context, pod Pod, decision Decision) {
+	s.mutex.Lock()
+	deferred := s.deferredRecorded[pod]
+	if decision.Defer {
+		s.deferredRecorded[pod] = true
+	} else {
+		delete(s.deferredRecorded, pod)
+	}
+	s.mutex.Unlock()
+
+	if decision.Defer && !deferred {
+		s.recordEvent(ctx, pod, decision, corev1.EventTypeNormal, EventReasonShutdownDeferred, fmt.Sprintf("Node termination is deferred by provider %s: %s", decision.Provider, decision.Reason))
+	}
+	if !decision.Defer && deferred {
+		s.recordEvent(ctx, pod, decision, corev1.EventTypeNormal, EventReasonShutdownAllowed, fmt.Sprintf("Node termination is not deferred anymore by provider %s: %s", decision.Provider, decision.Reason))
+	}
+}
+
+func (s *Service) recordEvent(ctx context.Context, pod Pod, decision Decision, eventType, reason, message string) {
+	if s.eventRecorder == nil {
+		return
+	}
+
+	s.eventRecorder.Event(ctx, pod, decision, eventType, reason, message)
+}
+
+// notifyAllowed remembers that the last decision allowed node termination and
+// wakes up all goroutines waiting for a decision allowing it.
+func (s *Service) notifyAllowed() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.lastAllowed = true
+	close(s.allowed)
+	s.allowed = make(chan struct{})
+}
+
+// markDeferred remembers the time node termination of the given POD was
+// deferred for the first time and returns it.
+func (s *Service) markDeferred(pod Pod) time.Time {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	deferredSince, ok := s.deferredSince[pod]
+	if !ok {
+		deferredSince = time.Now()
+		s.deferredSince[pod] = deferredSince
+	}
+
+	return deferredSince
+}
+
+// forgetDeferred drops the time node termination of the given POD was deferred
+// for the first time, once providers allow it.
+func (s *Service) forgetDeferred(pod Pod) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	delete(s.deferredSince, pod)
+}
+
+// forget drops all state kept for the given POD, e.g. once it has been
+// deleted in multi-tenant mode.
+func (s *Service) forget(pod Pod) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	delete(s.consecutiveFailures, pod)
+	delete(s.deferredRecorded, pod)
+	delete(s.lastFailure, pod)
+	delete(s.deferredSince, pod)
+}
+
+// formatBootErrs returns the given boot errors by component in a stable order.
+func formatBootErrs(bootErrs map[string]error) string {
+	var components []string
+	for c := range bootErrs {
+		components = append(components, c)
+	}
+	sort.Strings(components)
+
+	var messages []string
+	for _, c := range components {
+		messages = append(messages, fmt.Sprintf("%s: %s", c, bootErrs[c]))
+	}
+
+	return strings.Join(messages, "; ")
+}
+
+// isStateless returns true when the given context carries a DrainerConfig
+// target given by the caller. Decisions for such a target do not describe the
+// POD, so they must not affect its deadline, failure count or transitions.
+func isStateless(ctx context.Context) bool {
+	_, ok := DrainerConfigTargetFromContext(ctx)
+	return ok
 }
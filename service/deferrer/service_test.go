@@ -2,7 +2,8 @@ package deferrer
 
 import (
 	"context"
-	"os"
+	"reflect"
+	"strings"
 	"testing"
 	"time"
 
@@ -10,8 +11,13 @@ import (
 	"github.com/giantswarm/apiextensions/pkg/clientset/versioned/fake"
 	"github.com/giantswarm/microerror"
 	"github.com/giantswarm/micrologger/microloggertest"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	k8sfake "k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+	"k8s.io/client-go/tools/cache"
 )
 
 func Test_ShouldDefer(t *testing.T) {
@@ -21,6 +27,7 @@ func Test_ShouldDefer(t *testing.T) {
 		podNamespace        string
 		drainerConfig       *v1alpha1.DrainerConfig
 		expectedShouldDefer bool
+		expectedReason      string
 		errorMatcher        func(error) bool
 	}{
 		{
@@ -29,6 +36,7 @@ func Test_ShouldDefer(t *testing.T) {
 			podNamespace:        "bar",
 			drainerConfig:       nil,
 			expectedShouldDefer: true,
+			expectedReason:      ReasonNoDrainerConfig,
 			errorMatcher:        nil,
 		},
 		{
@@ -44,6 +52,7 @@ func Test_ShouldDefer(t *testing.T) {
 				Status: v1alpha1.DrainerConfigStatus{},
 			},
 			expectedShouldDefer: true,
+			expectedReason:      ReasonNoConditions,
 			errorMatcher:        nil,
 		},
 		{
@@ -67,6 +76,7 @@ func Test_ShouldDefer(t *testing.T) {
 				},
 			},
 			expectedShouldDefer: false,
+			expectedReason:      ReasonDrained,
 			errorMatcher:        nil,
 		},
 		{
@@ -90,6 +100,7 @@ func Test_ShouldDefer(t *testing.T) {
 				},
 			},
 			expectedShouldDefer: false,
+			expectedReason:      ReasonTimeout,
 			errorMatcher:        nil,
 		},
 		{
@@ -98,6 +109,7 @@ func Test_ShouldDefer(t *testing.T) {
 			podNamespace:        "bar",
 			drainerConfig:       nil,
 			expectedShouldDefer: false,
+			expectedReason:      "",
 			errorMatcher:        func(err error) bool { return microerror.Cause(err) == invalidConfigError },
 		},
 		{
@@ -106,6 +118,7 @@ func Test_ShouldDefer(t *testing.T) {
 			podNamespace:        "",
 			drainerConfig:       nil,
 			expectedShouldDefer: false,
+			expectedReason:      "",
 			errorMatcher:        func(err error) bool { return microerror.Cause(err) == invalidConfigError },
 		},
 	}
@@ -120,15 +133,26 @@ func Test_ShouldDefer(t *testing.T) {
 
 			client := fake.NewSimpleClientset(objs...)
 
-			s := &Service{
-				g8sClient: client,
-				logger:    microloggertest.New(),
+			p, err := NewDrainerConfigProvider(DrainerConfigProviderConfig{
+				G8sClient: client,
+				Logger:    microloggertest.New(),
+			})
+			if err != nil {
+				t.Fatalf("error == %#v, want nil", err)
 			}
 
-			os.Setenv(EnvKeyMyPodName, tc.podName)
-			os.Setenv(EnvKeyMyPodNamespace, tc.podNamespace)
+			s, err := New(Config{
+				Logger:    microloggertest.New(),
+				Pod:       Pod{Name: tc.podName, Namespace: tc.podNamespace},
+				Providers: []Provider{p},
+			})
 
-			shouldDefer, err := s.ShouldDefer(context.TODO())
+			// Missing pod name and namespace are rejected when creating the
+			// service.
+			var shouldDefer bool
+			if err == nil {
+				shouldDefer, err = s.ShouldDefer(context.TODO())
+			}
 
 			switch {
 			case err == nil && tc.errorMatcher == nil:
@@ -141,9 +165,849 @@ func Test_ShouldDefer(t *testing.T) {
 				t.Fatalf("error == %#v, want matching", err)
 			}
 
+			if tc.errorMatcher != nil {
+				return
+			}
+
 			if shouldDefer != tc.expectedShouldDefer {
 				t.Fatalf("ShouldDefer() == %v, want %v", shouldDefer, tc.expectedShouldDefer)
 			}
+
+			decision, _ := s.Decide(context.TODO())
+			if decision.Reason != tc.expectedReason {
+				t.Fatalf("Decide().Reason == %q, want %q", decision.Reason, tc.expectedReason)
+			}
+		})
+	}
+}
+
+func Test_ShouldDefer_InformerCache(t *testing.T) {
+	drainerConfig := &v1alpha1.DrainerConfig{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "foo",
+			Namespace: "bar",
+		},
+
+		Status: v1alpha1.DrainerConfigStatus{
+			Conditions: []v1alpha1.DrainerConfigStatusCondition{
+				v1alpha1.DrainerConfigStatusCondition{
+					LastTransitionTime: v1alpha1.DeepCopyTime{Time: time.Now()},
+					Status:             v1alpha1.DrainerConfigStatusStatusTrue,
+					Type:               v1alpha1.DrainerConfigStatusTypeDrained,
+				},
+			},
+		},
+	}
+
+	client := fake.NewSimpleClientset(drainerConfig)
+
+	p, err := NewDrainerConfigProvider(DrainerConfigProviderConfig{
+		G8sClient: client,
+		Logger:    microloggertest.New(),
+	})
+	if err != nil {
+		t.Fatalf("error == %#v, want nil", err)
+	}
+
+	s, err := New(Config{
+		Logger:    microloggertest.New(),
+		Pod:       Pod{Name: "foo", Namespace: "bar"},
+		Providers: []Provider{p},
+	})
+	if err != nil {
+		t.Fatalf("error == %#v, want nil", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	err = s.Boot(ctx)
+	if err != nil {
+		t.Fatalf("error == %#v, want nil", err)
+	}
+
+	if !cache.WaitForCacheSync(ctx.Done(), p.informer.HasSynced) {
+		t.Fatalf("informer cache did not sync")
+	}
+
+	shouldDefer, err := s.ShouldDefer(ctx)
+	if err != nil {
+		t.Fatalf("error == %#v, want nil", err)
+	}
+	if shouldDefer {
+		t.Fatalf("ShouldDefer() == %v, want %v", shouldDefer, false)
+	}
+
+	for _, a := range client.Actions() {
+		if a.GetVerb() == "get" {
+			t.Fatalf("found %s action for %s, want drainerconfig to be read from informer cache", a.GetVerb(), a.GetResource().Resource)
+		}
+	}
+}
+
+func Test_Wait(t *testing.T) {
+	drainerConfig := &v1alpha1.DrainerConfig{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "foo",
+			Namespace: "bar",
+		},
+
+		Status: v1alpha1.DrainerConfigStatus{},
+	}
+
+	client := fake.NewSimpleClientset(drainerConfig)
+
+	p, err := NewDrainerConfigProvider(DrainerConfigProviderConfig{
+		G8sClient: client,
+		Logger:    microloggertest.New(),
+	})
+	if err != nil {
+		t.Fatalf("error == %#v, want nil", err)
+	}
+
+	s, err := New(Config{
+		Logger:    microloggertest.New(),
+		Pod:       Pod{Name: "foo", Namespace: "bar"},
+		Providers: []Provider{p},
+	})
+	if err != nil {
+		t.Fatalf("error == %#v, want nil", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	err = s.Boot(ctx)
+	if err != nil {
+		t.Fatalf("error == %#v, want nil", err)
+	}
+
+	if !cache.WaitForCacheSync(ctx.Done(), p.informer.HasSynced) {
+		t.Fatalf("informer cache did not sync")
+	}
+
+	{
+		waitCtx, waitCancel := context.WithTimeout(ctx, 100*time.Millisecond)
+		defer waitCancel()
+
+		decision, err := s.Wait(waitCtx)
+		if err != nil {
+			t.Fatalf("error == %#v, want nil", err)
+		}
+		if !decision.Defer {
+			t.Fatalf("Wait().Defer == %v, want %v", decision.Defer, true)
+		}
+		if decision.Reason != ReasonNoConditions {
+			t.Fatalf("Wait().Reason == %q, want %q", decision.Reason, ReasonNoConditions)
+		}
+	}
+
+	{
+		type result struct {
+			decision Decision
+			err      error
+		}
+		results := make(chan result, 1)
+
+		go func() {
+			waitCtx, waitCancel := context.WithTimeout(ctx, 10*time.Second)
+			defer waitCancel()
+
+			decision, err := s.Wait(waitCtx)
+			results <- result{decision: decision, err: err}
+		}()
+
+		drained := drainerConfig.DeepCopy()
+		drained.Status.Conditions = append(drained.Status.Conditions, drained.Status.NewDrainedCondition())
+
+		_, err = client.CoreV1alpha1().DrainerConfigs("bar").UpdateStatus(drained)
+		if err != nil {
+			t.Fatalf("error == %#v, want nil", err)
+		}
+
+		r := <-results
+		if r.err != nil {
+			t.Fatalf("error == %#v, want nil", r.err)
+		}
+		if r.decision.Defer {
+			t.Fatalf("Wait().Defer == %v, want %v", r.decision.Defer, false)
+		}
+		if r.decision.Reason != ReasonDrained {
+			t.Fatalf("Wait().Reason == %q, want %q", r.decision.Reason, ReasonDrained)
+		}
+	}
+}
+
+func Test_ShouldDefer_FailurePolicy(t *testing.T) {
+	testCases := []struct {
+		name                 string
+		failurePolicy        string
+		failureThreshold     int
+		expectedShouldDefers []bool
+	}{
+		{
+			name:                 "case 0: defer on failures with defer policy",
+			failurePolicy:        FailurePolicyDefer,
+			expectedShouldDefers: []bool{true, true, true},
+		},
+		{
+			name:                 "case 1: allow on failures with allow policy",
+			failurePolicy:        FailurePolicyAllow,
+			expectedShouldDefers: []bool{false, false, false},
+		},
+		{
+			name:                 "case 2: allow after two failures with allow-after-failures policy",
+			failurePolicy:        FailurePolicyAllowAfterFailures,
+			failureThreshold:     2,
+			expectedShouldDefers: []bool{true, false, false},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			client := fake.NewSimpleClientset()
+			client.PrependReactor("get", "drainerconfigs", func(action k8stesting.Action) (bool, runtime.Object, error) {
+				return true, nil, apierrors.NewServiceUnavailable("apiserver is down")
+			})
+
+			p, err := NewDrainerConfigProvider(DrainerConfigProviderConfig{
+				G8sClient: client,
+				Logger:    microloggertest.New(),
+			})
+			if err != nil {
+				t.Fatalf("error == %#v, want nil", err)
+			}
+
+			s, err := New(Config{
+				Logger:    microloggertest.New(),
+				Pod:       Pod{Name: "foo", Namespace: "bar"},
+				Providers: []Provider{p},
+
+				FailurePolicy:    tc.failurePolicy,
+				FailureThreshold: tc.failureThreshold,
+			})
+			if err != nil {
+				t.Fatalf("error == %#v, want nil", err)
+			}
+
+			// Every call counts as a separate failure.
+			s.retryInterval = 0
+
+			for i, expected := range tc.expectedShouldDefers {
+				shouldDefer, err := s.ShouldDefer(context.TODO())
+				if err != nil {
+					t.Fatalf("call %d: error == %#v, want nil", i, err)
+				}
+				if shouldDefer != expected {
+					t.Fatalf("call %d: ShouldDefer() == %v, want %v", i, shouldDefer, expected)
+				}
+			}
+		})
+	}
+}
+
+func Test_Watch_FailureThreshold(t *testing.T) {
+	failing := &testProvider{name: "failing", err: microerror.New("test")}
+
+	s, err := New(Config{
+		Logger:    microloggertest.New(),
+		Pod:       Pod{Name: "foo", Namespace: "bar"},
+		Providers: []Provider{failing},
+
+		FailurePolicy:    FailurePolicyAllowAfterFailures,
+		FailureThreshold: 2,
+	})
+	if err != nil {
+		t.Fatalf("error == %#v, want nil", err)
+	}
+	s.retryInterval = 500 * time.Millisecond
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	// Two watchers evaluate the same failed lookups. Their first decisions
+	// count as a single failure, so both still defer node termination.
+	type result struct {
+		decisions []Decision
+		err       error
+	}
+	results := make(chan result, 2)
+	for i := 0; i < 2; i++ {
+		go func() {
+			var r result
+			r.err = s.Watch(ctx, func(decision Decision) bool {
+				r.decisions = append(r.decisions, decision)
+				return decision.Defer
+			})
+			results <- r
+		}()
+	}
+
+	for i := 0; i < 2; i++ {
+		r := <-results
+		if r.err != nil {
+			t.Fatalf("error == %#v, want nil", r.err)
+		}
+		if len(r.decisions) < 2 {
+			t.Fatalf("watcher %d got %d decisions, want at least 2", i, len(r.decisions))
+		}
+		if !r.decisions[0].Defer {
+			t.Fatalf("watcher %d: first decision defers == false, want true", i)
+		}
+		if r.decisions[len(r.decisions)-1].Defer {
+			t.Fatalf("watcher %d: last decision defers == true, want false after retries", i)
+		}
+	}
+}
+
+func Test_Decide_Deadline(t *testing.T) {
+	testCases := []struct {
+		name                string
+		deadline            time.Duration
+		annotations         map[string]string
+		deferredFor         time.Duration
+		expectedShouldDefer bool
+		expectedReason      string
+	}{
+		{
+			name:                "case 0: defer without deadline",
+			deadline:            0,
+			deferredFor:         time.Hour,
+			expectedShouldDefer: true,
+			expectedReason:      ReasonNoConditions,
+		},
+		{
+			name:                "case 1: defer before deadline is exceeded",
+			deadline:            10 * time.Minute,
+			deferredFor:         time.Minute,
+			expectedShouldDefer: true,
+			expectedReason:      ReasonNoConditions,
+		},
+		{
+			name:                "case 2: do not defer after deadline is exceeded",
+			deadline:            10 * time.Minute,
+			deferredFor:         time.Hour,
+			expectedShouldDefer: false,
+			expectedReason:      ReasonDeadlineExceeded,
+		},
+		{
+			name:     "case 3: do not defer after deadline from annotation is exceeded",
+			deadline: 0,
+			annotations: map[string]string{
+				AnnotationMaxDefer: "5m",
+			},
+			deferredFor:         10 * time.Minute,
+			expectedShouldDefer: false,
+			expectedReason:      ReasonDeadlineExceeded,
+		},
+		{
+			name:     "case 4: ignore invalid deadline annotation",
+			deadline: time.Hour,
+			annotations: map[string]string{
+				AnnotationMaxDefer: "soon",
+			},
+			deferredFor:         10 * time.Minute,
+			expectedShouldDefer: true,
+			expectedReason:      ReasonNoConditions,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			drainerConfig := &v1alpha1.DrainerConfig{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        "foo",
+					Namespace:   "bar",
+					Annotations: tc.annotations,
+				},
+			}
+
+			p, err := NewDrainerConfigProvider(DrainerConfigProviderConfig{
+				G8sClient: fake.NewSimpleClientset(drainerConfig),
+				Logger:    microloggertest.New(),
+			})
+			if err != nil {
+				t.Fatalf("error == %#v, want nil", err)
+			}
+
+			s, err := New(Config{
+				Logger:    microloggertest.New(),
+				Pod:       Pod{Name: "foo", Namespace: "bar"},
+				Providers: []Provider{p},
+
+				Deadline: tc.deadline,
+			})
+			if err != nil {
+				t.Fatalf("error == %#v, want nil", err)
+			}
+
+			s.deferredSince[Pod{Name: "foo", Namespace: "bar"}] = time.Now().Add(-tc.deferredFor)
+
+			decision, err := s.Decide(context.TODO())
+			if err != nil {
+				t.Fatalf("error == %#v, want nil", err)
+			}
+
+			if decision.Defer != tc.expectedShouldDefer {
+				t.Fatalf("Decide().Defer == %v, want %v", decision.Defer, tc.expectedShouldDefer)
+			}
+			if decision.Reason != tc.expectedReason {
+				t.Fatalf("Decide().Reason == %q, want %q", decision.Reason, tc.expectedReason)
+			}
+		})
+	}
+}
+
+type testProvider struct {
+	name     string
+	decision Decision
+	err      error
+	bootErr  error
+	booted   bool
+}
+
+func (p *testProvider) Boot(ctx context.Context, pod Pod) error {
+	p.booted = true
+	return p.bootErr
+}
+func (p *testProvider) Changes() <-chan struct{} { return nil }
+func (p *testProvider) Decide(ctx context.Context, pod Pod) (Decision, error) {
+	return p.decision, p.err
+}
+func (p *testProvider) Name() string { return p.name }
+
+func Test_Decide_Combination(t *testing.T) {
+	deferring := &testProvider{name: "deferring", decision: Decision{Defer: true, Reason: ReasonNoConditions}}
+	allowing := &testProvider{name: "allowing", decision: Decision{Defer: false, Reason: ReasonDrained}}
+	failing := &testProvider{name: "failing", err: microerror.New("test")}
+
+	testCases := []struct {
+		name                string
+		combination         string
+		providers           []Provider
+		expectedShouldDefer bool
+		expectedProvider    string
+	}{
+		{
+			name:                "case 0: defer with all combination when one provider defers",
+			combination:         CombinationAll,
+			providers:           []Provider{allowing, deferring},
+			expectedShouldDefer: true,
+			expectedProvider:    "deferring",
+		},
+		{
+			name:                "case 1: do not defer with all combination when all providers allow",
+			combination:         CombinationAll,
+			providers:           []Provider{allowing, allowing},
+			expectedShouldDefer: false,
+			expectedProvider:    "allowing",
+		},
+		{
+			name:                "case 2: do not defer with any combination when one provider allows",
+			combination:         CombinationAny,
+			providers:           []Provider{deferring, allowing},
+			expectedShouldDefer: false,
+			expectedProvider:    "allowing",
+		},
+		{
+			name:                "case 3: defer with any combination when all providers defer",
+			combination:         CombinationAny,
+			providers:           []Provider{deferring, failing},
+			expectedShouldDefer: true,
+			expectedProvider:    "deferring",
+		},
+		{
+			name:                "case 4: defer with all combination when one provider fails",
+			combination:         CombinationAll,
+			providers:           []Provider{allowing, failing},
+			expectedShouldDefer: true,
+			expectedProvider:    "failing",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			s, err := New(Config{
+				Logger:    microloggertest.New(),
+				Pod:       Pod{Name: "foo", Namespace: "bar"},
+				Providers: tc.providers,
+
+				Combination: tc.combination,
+			})
+			if err != nil {
+				t.Fatalf("error == %#v, want nil", err)
+			}
+
+			decision, err := s.Decide(context.TODO())
+			if err != nil {
+				t.Fatalf("error == %#v, want nil", err)
+			}
+
+			if decision.Defer != tc.expectedShouldDefer {
+				t.Fatalf("Decide().Defer == %v, want %v", decision.Defer, tc.expectedShouldDefer)
+			}
+			if decision.Provider != tc.expectedProvider {
+				t.Fatalf("Decide().Provider == %q, want %q", decision.Provider, tc.expectedProvider)
+			}
+		})
+	}
+}
+
+func Test_DecideFor_MultiTenant(t *testing.T) {
+	drainedConfig := &v1alpha1.DrainerConfig{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "foo",
+			Namespace: "bar",
+		},
+
+		Status: v1alpha1.DrainerConfigStatus{
+			Conditions: []v1alpha1.DrainerConfigStatusCondition{
+				v1alpha1.DrainerConfigStatusCondition{
+					LastTransitionTime: v1alpha1.DeepCopyTime{Time: time.Now()},
+					Status:             v1alpha1.DrainerConfigStatusStatusTrue,
+					Type:               v1alpha1.DrainerConfigStatusTypeDrained,
+				},
+			},
+		},
+	}
+	pendingConfig := &v1alpha1.DrainerConfig{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "baz",
+			Namespace: "qux",
+		},
+	}
+
+	client := fake.NewSimpleClientset(drainedConfig, pendingConfig)
+
+	p, err := NewDrainerConfigProvider(DrainerConfigProviderConfig{
+		G8sClient: client,
+		Logger:    microloggertest.New(),
+	})
+	if err != nil {
+		t.Fatalf("error == %#v, want nil", err)
+	}
+
+	k8sClient := k8sfake.NewSimpleClientset(
+		&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "bar"}},
+		&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "baz", Namespace: "qux"}},
+		&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "qux"}},
+	)
+
+	s, err := New(Config{
+		K8sClient:   k8sClient,
+		Logger:      microloggertest.New(),
+		MultiTenant: true,
+		PodInformer: NewPodInformer(k8sClient),
+		Providers:   []Provider{p},
+	})
+	if err != nil {
+		t.Fatalf("error == %#v, want nil", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	err = s.Boot(ctx)
+	if err != nil {
+		t.Fatalf("error == %#v, want nil", err)
+	}
+
+	if !cache.WaitForCacheSync(ctx.Done(), p.informer.HasSynced, s.podInformer.HasSynced) {
+		t.Fatalf("informer cache did not sync")
+	}
+
+	testCases := []struct {
+		name           string
+		pod            Pod
+		expectedDefer  bool
+		expectedReason string
+		errorMatcher   func(error) bool
+	}{
+		{
+			name:           "case 0: should not defer for pod with drained drainerconfig",
+			pod:            Pod{Name: "foo", Namespace: "bar"},
+			expectedDefer:  false,
+			expectedReason: ReasonDrained,
+		},
+		{
+			name:           "case 1: should defer for pod in other namespace without conditions",
+			pod:            Pod{Name: "baz", Namespace: "qux"},
+			expectedDefer:  true,
+			expectedReason: ReasonNoConditions,
+		},
+		{
+			name:           "case 2: should defer for pod without drainerconfig",
+			pod:            Pod{Name: "foo", Namespace: "qux"},
+			expectedDefer:  true,
+			expectedReason: ReasonNoDrainerConfig,
+		},
+		{
+			name:         "case 3: should reject pod which does not exist",
+			pod:          Pod{Name: "unknown", Namespace: "qux"},
+			errorMatcher: IsNotFound,
+		},
+		{
+			name:         "case 4: should reject pod without namespace",
+			pod:          Pod{Name: "foo"},
+			errorMatcher: IsInvalidRequest,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			decision, err := s.DecideFor(ctx, tc.pod)
+
+			switch {
+			case err == nil && tc.errorMatcher == nil:
+				// correct; carry on
+			case err != nil && tc.errorMatcher == nil:
+				t.Fatalf("error == %#v, want nil", err)
+			case err == nil && tc.errorMatcher != nil:
+				t.Fatalf("error == nil, want non-nil")
+			case !tc.errorMatcher(err):
+				t.Fatalf("error == %#v, want matching", err)
+			}
+
+			if tc.errorMatcher != nil {
+				return
+			}
+
+			if decision.Defer != tc.expectedDefer {
+				t.Fatalf("DecideFor().Defer == %v, want %v", decision.Defer, tc.expectedDefer)
+			}
+			if decision.Reason != tc.expectedReason {
+				t.Fatalf("DecideFor().Reason == %q, want %q", decision.Reason, tc.expectedReason)
+			}
 		})
 	}
+
+	for _, a := range client.Actions() {
+		if a.GetVerb() == "get" {
+			t.Fatalf("found %s action for %s, want drainerconfigs to be read from shared informer cache", a.GetVerb(), a.GetResource().Resource)
+		}
+	}
+	for _, a := range k8sClient.Actions() {
+		if a.GetVerb() == "get" {
+			t.Fatalf("found %s action for %s, want pods to be read from informer cache", a.GetVerb(), a.GetResource().Resource)
+		}
+	}
+
+	_, err = s.Decide(ctx)
+	if !IsInvalidConfig(err) {
+		t.Fatalf("error == %#v, want invalidConfigError", err)
+	}
+
+	if len(s.deferredSince) != 2 || len(s.deferredRecorded) != 2 {
+		t.Fatalf("state kept for %d and %d pods, want 2", len(s.deferredSince), len(s.deferredRecorded))
+	}
+
+	err = k8sClient.CoreV1().Pods("qux").Delete("baz", &metav1.DeleteOptions{})
+	if err != nil {
+		t.Fatalf("error == %#v, want nil", err)
+	}
+
+	var kept int
+	for i := 0; i < 50; i++ {
+		s.mutex.Lock()
+		kept = len(s.deferredSince)
+		s.mutex.Unlock()
+
+		if kept == 1 {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if kept != 1 {
+		t.Fatalf("state kept for %d pods, want 1 after pod deletion", kept)
+	}
+}
+
+func Test_Boot(t *testing.T) {
+	failing := &testProvider{name: "failing", bootErr: microerror.New("test")}
+	booting := &testProvider{name: "booting"}
+
+	s, err := New(Config{
+		Logger:    microloggertest.New(),
+		Pod:       Pod{Name: "foo", Namespace: "bar"},
+		Providers: []Provider{failing, booting},
+	})
+	if err != nil {
+		t.Fatalf("error == %#v, want nil", err)
+	}
+
+	err = s.Boot(context.Background())
+	if !IsBootFailed(err) {
+		t.Fatalf("error == %#v, want bootFailedError", err)
+	}
+	if !booting.booted {
+		t.Fatalf("provider booting not booted, want booted after failing provider")
+	}
+
+	err = s.Ready(context.Background())
+	if !IsNotReady(err) {
+		t.Fatalf("error == %#v, want notReadyError", err)
+	}
+	if !strings.Contains(err.Error(), "provider failing") {
+		t.Fatalf("error == %q, want failing provider named", err.Error())
+	}
+}
+
+func Test_Allowed(t *testing.T) {
+	p := &testProvider{name: "test", decision: Decision{Defer: true, Reason: ReasonNoConditions}}
+
+	s, err := New(Config{
+		Logger:    microloggertest.New(),
+		Pod:       Pod{Name: "foo", Namespace: "bar"},
+		Providers: []Provider{p},
+	})
+	if err != nil {
+		t.Fatalf("error == %#v, want nil", err)
+	}
+
+	allowed := s.Allowed()
+
+	_, err = s.Decide(context.Background())
+	if err != nil {
+		t.Fatalf("error == %#v, want nil", err)
+	}
+
+	select {
+	case <-allowed:
+		t.Fatalf("Allowed() closed, want open after deferring decision")
+	default:
+	}
+
+	p.decision = Decision{Defer: false, Reason: ReasonDrained}
+
+	_, err = s.Decide(context.Background())
+	if err != nil {
+		t.Fatalf("error == %#v, want nil", err)
+	}
+
+	select {
+	case <-allowed:
+	default:
+		t.Fatalf("Allowed() open, want closed after allowing decision")
+	}
+
+	// The first client was answered before the shutdown began. The shutdown
+	// waits for the second client asking afterwards to be answered.
+	shutdown := s.Allowed()
+
+	select {
+	case <-shutdown:
+		t.Fatalf("Allowed() closed, want open until the next allowing decision")
+	default:
+	}
+
+	_, err = s.Decide(context.Background())
+	if err != nil {
+		t.Fatalf("error == %#v, want nil", err)
+	}
+
+	select {
+	case <-shutdown:
+	default:
+		t.Fatalf("Allowed() open, want closed after allowing decision for second client")
+	}
+}
+
+func Test_Decide_CallerTarget(t *testing.T) {
+	p := &testProvider{name: "test", decision: Decision{Defer: true, Reason: ReasonNoConditions}}
+
+	s, err := New(Config{
+		Deadline:  time.Hour,
+		Logger:    microloggertest.New(),
+		Pod:       Pod{Name: "foo", Namespace: "bar"},
+		Providers: []Provider{p},
+	})
+	if err != nil {
+		t.Fatalf("error == %#v, want nil", err)
+	}
+
+	_, err = s.Decide(context.Background())
+	if err != nil {
+		t.Fatalf("error == %#v, want nil", err)
+	}
+
+	allowed := s.Allowed()
+
+	p.decision = Decision{Defer: false, Reason: ReasonDrained}
+
+	ctx := NewDrainerConfigTargetContext(context.Background(), DrainerConfigTarget{Namespace: "other"})
+
+	d, err := s.Decide(ctx)
+	if err != nil {
+		t.Fatalf("error == %#v, want nil", err)
+	}
+	if d.Defer {
+		t.Fatalf("Defer == true, want false for caller target")
+	}
+
+	select {
+	case <-allowed:
+		t.Fatalf("Allowed() closed, want open after caller target decision")
+	default:
+	}
+	if s.LastAllowed() {
+		t.Fatalf("LastAllowed() == true, want false after caller target decision")
+	}
+	if _, ok := s.deferredSince[s.pod]; !ok {
+		t.Fatalf("deferred since not tracked, want kept after caller target decision")
+	}
+}
+
+type testEventRecorder struct {
+	reasons []string
+}
+
+func (r *testEventRecorder) Event(ctx context.Context, pod Pod, decision Decision, eventType, reason, message string) {
+	r.reasons = append(r.reasons, reason)
+}
+
+func Test_Decide_Events(t *testing.T) {
+	p := &testProvider{name: "test"}
+	r := &testEventRecorder{}
+
+	s, err := New(Config{
+		EventRecorder: r,
+		Logger:        microloggertest.New(),
+		Pod:           Pod{Name: "foo", Namespace: "bar"},
+		Providers:     []Provider{p},
+	})
+	if err != nil {
+		t.Fatalf("error == %#v, want nil", err)
+	}
+
+	steps := []struct {
+		decision Decision
+		err      error
+	}{
+		{decision: Decision{Defer: false, Reason: ReasonNoDrainerConfig}},
+		{decision: Decision{Defer: true, Reason: ReasonNoConditions}},
+		{decision: Decision{Defer: true, Reason: ReasonNoConditions}},
+		{err: microerror.New("test")},
+		{err: microerror.New("test")},
+		{decision: Decision{Defer: false, Reason: ReasonDrained}},
+		{decision: Decision{Defer: false, Reason: ReasonDrained}},
+	}
+
+	for _, step := range steps {
+		p.decision = step.decision
+		p.err = step.err
+
+		_, err = s.Decide(context.Background())
+		if err != nil {
+			t.Fatalf("error == %#v, want nil", err)
+		}
+	}
+
+	expectedReasons := []string{
+		EventReasonShutdownDeferred,
+		EventReasonLookupFailed,
+		EventReasonShutdownAllowed,
+	}
+	if !reflect.DeepEqual(r.reasons, expectedReasons) {
+		t.Fatalf("reasons == %v, want %v", r.reasons, expectedReasons)
+	}
 }
@@ -4,6 +4,15 @@ import (
 	"github.com/giantswarm/microerror"
 )
 
+var accessDeniedError = &microerror.Error{
+	Kind: "accessDeniedError",
+}
+
+// IsAccessDenied asserts accessDeniedError.
+func IsAccessDenied(err error) bool {
+	return microerror.Cause(err) == accessDeniedError
+}
+
 var invalidConfigError = &microerror.Error{
 	Kind: "invalidConfigError",
 }
@@ -12,3 +21,30 @@ var invalidConfigError = &microerror.Error{
 func IsInvalidConfig(err error) bool {
 	return microerror.Cause(err) == invalidConfigError
 }
+
+var invalidKubeConfigError = &microerror.Error{
+	Kind: "invalidKubeConfigError",
+}
+
+// IsInvalidKubeConfig asserts invalidKubeConfigError.
+func IsInvalidKubeConfig(err error) bool {
+	return microerror.Cause(err) == invalidKubeConfigError
+}
+
+var tlsFileNotFoundError = &microerror.Error{
+	Kind: "tlsFileNotFoundError",
+}
+
+// IsTLSFileNotFound asserts tlsFileNotFoundError.
+func IsTLSFileNotFound(err error) bool {
+	return microerror.Cause(err) == tlsFileNotFoundError
+}
+
+var unreachableAddressError = &microerror.Error{
+	Kind: "unreachableAddressError",
+}
+
+// IsUnreachableAddress asserts unreachableAddressError.
+func IsUnreachableAddress(err error) bool {
+	return microerror.Cause(err) == unreachableAddressError
+}
@@ -0,0 +1,32 @@
+package identity
+
+import (
+	"github.com/giantswarm/microerror"
+)
+
+var invalidConfigError = &microerror.Error{
+	Kind: "invalidConfigError",
+}
+
+// IsInvalidConfig asserts invalidConfigError.
+func IsInvalidConfig(err error) bool {
+	return microerror.Cause(err) == invalidConfigError
+}
+
+var invalidIdentityError = &microerror.Error{
+	Kind: "invalidIdentityError",
+}
+
+// IsInvalidIdentity asserts invalidIdentityError.
+func IsInvalidIdentity(err error) bool {
+	return microerror.Cause(err) == invalidIdentityError
+}
+
+var notResolvedError = &microerror.Error{
+	Kind: "notResolvedError",
+}
+
+// IsNotResolved asserts notResolvedError.
+func IsNotResolved(err error) bool {
+	return microerror.Cause(err) == notResolvedError
+}
@@ -0,0 +1,189 @@
+// Package identity resolves the name and namespace of the POD shutdown-deferrer
+// is running in from a configurable chain of sources.
+package identity
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/giantswarm/microerror"
+	"github.com/giantswarm/micrologger"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+const (
+	// SourceDownwardAPI reads the files "name" and "namespace" from
+	// Config.DownwardAPIDir, which are most conveniently created by a Downward
+	// API volume:
+	// https://kubernetes.io/docs/tasks/inject-data-application/downward-api-volume-expose-pod-information/
+	SourceDownwardAPI = "downwardapi"
+	// SourceEnv reads the environment variables with the keys defined in the
+	// constants EnvKeyMyPodName & EnvKeyMyPodNamespace, which are most
+	// conveniently set using the Downward API:
+	// https://kubernetes.io/docs/tasks/inject-data-application/environment-variable-expose-pod-information/
+	SourceEnv = "env"
+	// SourceFlag uses Config.Name and Config.Namespace.
+	SourceFlag = "flag"
+	// SourceHostname uses the hostname as POD name, which matches the POD name
+	// unless the POD spec overrides it. It is therefore not part of
+	// DefaultSources and has to be configured explicitly.
+	SourceHostname = "hostname"
+	// SourceServiceAccount reads the POD namespace from
+	// Config.ServiceAccountNamespaceFile, which is mounted together with the
+	// service account token.
+	SourceServiceAccount = "serviceaccount"
+)
+
+const (
+	EnvKeyMyPodName      = "MY_POD_NAME"
+	EnvKeyMyPodNamespace = "MY_POD_NAMESPACE"
+)
+
+const (
+	DefaultDownwardAPIDir              = "/etc/podinfo"
+	DefaultServiceAccountNamespaceFile = "/var/run/secrets/kubernetes.io/serviceaccount/namespace"
+)
+
+// DefaultSources is the order in which sources are asked when Config.Sources
+// is empty.
+var DefaultSources = []string{
+	SourceFlag,
+	SourceEnv,
+	SourceDownwardAPI,
+	SourceServiceAccount,
+}
+
+type Config struct {
+	Logger micrologger.Logger
+
+	// Sources are asked in the given order. The name and namespace are each
+	// taken from the first source providing them. Defaults to DefaultSources.
+	Sources []string
+
+	// Name and Namespace are used by SourceFlag.
+	Name      string
+	Namespace string
+
+	// DownwardAPIDir is used by SourceDownwardAPI. Defaults to
+	// DefaultDownwardAPIDir.
+	DownwardAPIDir string
+	// ServiceAccountNamespaceFile is used by SourceServiceAccount. Defaults to
+	// DefaultServiceAccountNamespaceFile.
+	ServiceAccountNamespaceFile string
+}
+
+// Identity is the name and namespace of the POD shutdown-deferrer is running
+// in.
+type Identity struct {
+	Name      string
+	Namespace string
+}
+
+// Resolve asks the configured sources for the POD name and namespace. Sources
+// which are not able to provide a value, e.g. because an environment variable
+// is not set or a file does not exist, are skipped. An error is returned when
+// the name or namespace cannot be resolved from any source or is not a valid
+// POD name or namespace.
+func Resolve(ctx context.Context, config Config) (Identity, error) {
+	if config.Logger == nil {
+		return Identity{}, microerror.Maskf(invalidConfigError, "%T.Logger must not be empty", config)
+	}
+
+	if len(config.Sources) == 0 {
+		config.Sources = DefaultSources
+	}
+	if config.DownwardAPIDir == "" {
+		config.DownwardAPIDir = DefaultDownwardAPIDir
+	}
+	if config.ServiceAccountNamespaceFile == "" {
+		config.ServiceAccountNamespaceFile = DefaultServiceAccountNamespaceFile
+	}
+
+	var identity Identity
+	for _, source := range config.Sources {
+		name, namespace, err := lookup(config, source)
+		if err != nil {
+			return Identity{}, microerror.Mask(err)
+		}
+
+		if identity.Name == "" && name != "" {
+			_ = config.Logger.LogCtx(ctx, "level", "debug", "message", fmt.Sprintf("found pod name %s from source %s", name, source))
+			identity.Name = name
+		}
+		if identity.Namespace == "" && namespace != "" {
+			_ = config.Logger.LogCtx(ctx, "level", "debug", "message", fmt.Sprintf("found pod namespace %s from source %s", namespace, source))
+			identity.Namespace = namespace
+		}
+	}
+
+	if identity.Name == "" {
+		return Identity{}, microerror.Maskf(notResolvedError, "pod name not found in sources %s", strings.Join(config.Sources, ", "))
+	}
+	if identity.Namespace == "" {
+		return Identity{}, microerror.Maskf(notResolvedError, "pod namespace not found in sources %s", strings.Join(config.Sources, ", "))
+	}
+
+	if errs := validation.IsDNS1123Subdomain(identity.Name); len(errs) != 0 {
+		return Identity{}, microerror.Maskf(invalidIdentityError, "pod name %q is invalid: %s", identity.Name, strings.Join(errs, ", "))
+	}
+	if errs := validation.IsDNS1123Label(identity.Namespace); len(errs) != 0 {
+		return Identity{}, microerror.Maskf(invalidIdentityError, "pod namespace %q is invalid: %s", identity.Namespace, strings.Join(errs, ", "))
+	}
+
+	return identity, nil
+}
+
+// lookup returns the POD name and namespace provided by the given source.
+// Either of them is empty when the source does not provide it.
+func lookup(config Config, source string) (string, string, error) {
+	switch source {
+	case SourceDownwardAPI:
+		name, err := readFile(filepath.Join(config.DownwardAPIDir, "name"))
+		if err != nil {
+			return "", "", microerror.Mask(err)
+		}
+		namespace, err := readFile(filepath.Join(config.DownwardAPIDir, "namespace"))
+		if err != nil {
+			return "", "", microerror.Mask(err)
+		}
+
+		return name, namespace, nil
+	case SourceEnv:
+		return os.Getenv(EnvKeyMyPodName), os.Getenv(EnvKeyMyPodNamespace), nil
+	case SourceFlag:
+		return config.Name, config.Namespace, nil
+	case SourceHostname:
+		name, err := os.Hostname()
+		if err != nil {
+			return "", "", microerror.Mask(err)
+		}
+
+		return name, "", nil
+	case SourceServiceAccount:
+		namespace, err := readFile(config.ServiceAccountNamespaceFile)
+		if err != nil {
+			return "", "", microerror.Mask(err)
+		}
+
+		return "", namespace, nil
+	}
+
+	return "", "", microerror.Maskf(invalidConfigError, "unknown source %q", source)
+}
+
+// readFile returns the trimmed content of the file at the given path or an
+// empty string if it does not exist.
+func readFile(path string) (string, error) {
+	b, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return "", nil
+	} else if err != nil {
+		return "", microerror.Mask(err)
+	}
+
+	return strings.TrimSpace(string(b)), nil
+}
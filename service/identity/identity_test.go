@@ -0,0 +1,159 @@
+package identity
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/giantswarm/micrologger/microloggertest"
+)
+
+func Test_Resolve(t *testing.T) {
+	hostname, err := os.Hostname()
+	if err != nil {
+		t.Fatalf("error == %#v, want nil", err)
+	}
+
+	dir, err := ioutil.TempDir("", "identity")
+	if err != nil {
+		t.Fatalf("error == %#v, want nil", err)
+	}
+	defer os.RemoveAll(dir)
+
+	downwardAPIDir := filepath.Join(dir, "podinfo")
+	namespaceFile := filepath.Join(dir, "namespace")
+	{
+		err = os.Mkdir(downwardAPIDir, 0755)
+		if err != nil {
+			t.Fatalf("error == %#v, want nil", err)
+		}
+		err = ioutil.WriteFile(filepath.Join(downwardAPIDir, "name"), []byte("downwardapi-name\n"), 0644)
+		if err != nil {
+			t.Fatalf("error == %#v, want nil", err)
+		}
+		err = ioutil.WriteFile(filepath.Join(downwardAPIDir, "namespace"), []byte("downwardapi-namespace\n"), 0644)
+		if err != nil {
+			t.Fatalf("error == %#v, want nil", err)
+		}
+		err = ioutil.WriteFile(namespaceFile, []byte("serviceaccount-namespace"), 0644)
+		if err != nil {
+			t.Fatalf("error == %#v, want nil", err)
+		}
+	}
+
+	testCases := []struct {
+		name             string
+		config           Config
+		env              map[string]string
+		expectedIdentity Identity
+		errorMatcher     func(error) bool
+	}{
+		{
+			name: "case 0: resolve from flags first",
+			config: Config{
+				Name:      "flag-name",
+				Namespace: "flag-namespace",
+			},
+			env: map[string]string{
+				EnvKeyMyPodName:      "env-name",
+				EnvKeyMyPodNamespace: "env-namespace",
+			},
+			expectedIdentity: Identity{Name: "flag-name", Namespace: "flag-namespace"},
+		},
+		{
+			name: "case 1: fall back to env when flags are empty",
+			env: map[string]string{
+				EnvKeyMyPodName:      "env-name",
+				EnvKeyMyPodNamespace: "env-namespace",
+			},
+			expectedIdentity: Identity{Name: "env-name", Namespace: "env-namespace"},
+		},
+		{
+			name: "case 2: resolve name and namespace from different sources",
+			config: Config{
+				Name: "flag-name",
+			},
+			expectedIdentity: Identity{Name: "flag-name", Namespace: "downwardapi-namespace"},
+		},
+		{
+			name: "case 3: resolve from downward api files",
+			config: Config{
+				Sources: []string{SourceDownwardAPI},
+			},
+			expectedIdentity: Identity{Name: "downwardapi-name", Namespace: "downwardapi-namespace"},
+		},
+		{
+			name: "case 4: resolve from hostname and service account namespace file",
+			config: Config{
+				Sources: []string{SourceEnv, SourceServiceAccount, SourceHostname},
+			},
+			expectedIdentity: Identity{Name: hostname, Namespace: "serviceaccount-namespace"},
+		},
+		{
+			name: "case 5: return notResolvedError when namespace is not found",
+			config: Config{
+				Sources: []string{SourceEnv, SourceHostname},
+			},
+			errorMatcher: IsNotResolved,
+		},
+		{
+			name: "case 6: return invalidConfigError for unknown source",
+			config: Config{
+				Sources: []string{SourceFlag, "foo"},
+				Name:    "flag-name",
+			},
+			errorMatcher: IsInvalidConfig,
+		},
+		{
+			name: "case 7: return invalidIdentityError for invalid name",
+			config: Config{
+				Name:      "Flag_Name",
+				Namespace: "flag-namespace",
+			},
+			errorMatcher: IsInvalidIdentity,
+		},
+		{
+			name: "case 8: return invalidIdentityError for invalid namespace",
+			config: Config{
+				Name:      "flag-name",
+				Namespace: "flag.namespace",
+			},
+			errorMatcher: IsInvalidIdentity,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			os.Unsetenv(EnvKeyMyPodName)
+			os.Unsetenv(EnvKeyMyPodNamespace)
+			for k, v := range tc.env {
+				os.Setenv(k, v)
+				defer os.Unsetenv(k)
+			}
+
+			c := tc.config
+			c.Logger = microloggertest.New()
+			c.DownwardAPIDir = downwardAPIDir
+			c.ServiceAccountNamespaceFile = namespaceFile
+
+			identity, err := Resolve(context.Background(), c)
+
+			switch {
+			case err == nil && tc.errorMatcher == nil:
+				// correct; carry on
+			case err != nil && tc.errorMatcher == nil:
+				t.Fatalf("error == %#v, want nil", err)
+			case err == nil && tc.errorMatcher != nil:
+				t.Fatalf("error == nil, want non-nil")
+			case !tc.errorMatcher(err):
+				t.Fatalf("error == %#v, want matching", err)
+			}
+
+			if identity != tc.expectedIdentity {
+				t.Fatalf("identity == %#v, want %#v", identity, tc.expectedIdentity)
+			}
+		})
+	}
+}
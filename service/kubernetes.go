@@ -0,0 +1,111 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"os"
+	"time"
+
+	"github.com/giantswarm/k8sclient/k8srestconfig"
+	"github.com/giantswarm/microerror"
+	"github.com/giantswarm/micrologger"
+	"github.com/spf13/viper"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+
+	"github.com/giantswarm/shutdown-deferrer/flag"
+)
+
+const (
+	// bootstrapBackoffInitial is the time waited after the first failed
+	// attempt to reach the API server.
+	bootstrapBackoffInitial = 1 * time.Second
+	// bootstrapBackoffCap is the maximum time waited between attempts to reach
+	// the API server.
+	bootstrapBackoffCap = 30 * time.Second
+)
+
+// newRESTConfig creates the Kubernetes REST config from the given flags. Other
+// than k8srestconfig it validates that the configured TLS files exist and
+// returns typed errors, so that misconfiguration is reported clearly.
+func newRESTConfig(logger micrologger.Logger, f *flag.Flag, v *viper.Viper) (*rest.Config, error) {
+	c := k8srestconfig.Config{
+		Logger: logger,
+
+		Address:    v.GetString(f.Service.Kubernetes.Address),
+		InCluster:  v.GetBool(f.Service.Kubernetes.InCluster),
+		KubeConfig: v.GetString(f.Service.Kubernetes.KubeConfig),
+		TLS: k8srestconfig.ConfigTLS{
+			CAFile:  v.GetString(f.Service.Kubernetes.TLS.CAFile),
+			CrtFile: v.GetString(f.Service.Kubernetes.TLS.CrtFile),
+			KeyFile: v.GetString(f.Service.Kubernetes.TLS.KeyFile),
+		},
+	}
+
+	for _, file := range []string{c.TLS.CAFile, c.TLS.CrtFile, c.TLS.KeyFile} {
+		if file == "" {
+			continue
+		}
+
+		_, err := os.Stat(file)
+		if err != nil {
+			return nil, microerror.Maskf(tlsFileNotFoundError, "%s", err)
+		}
+	}
+
+	restConfig, err := k8srestconfig.New(c)
+	if err != nil {
+		return nil, microerror.Maskf(invalidKubeConfigError, "%s", err)
+	}
+
+	return restConfig, nil
+}
+
+// waitForAPIServer requests the version of the API server until it succeeds
+// or the given timeout is reached, backing off exponentially between
+// attempts. That way the deferrer does not crash-loop when it is started
+// before the POD network is up. Waiting between attempts is bounded by the
+// timeout and the given context. A zero timeout disables waiting.
+func waitForAPIServer(ctx context.Context, logger micrologger.Logger, k8sClient kubernetes.Interface, timeout time.Duration) error {
+	if timeout == 0 {
+		return nil
+	}
+
+	backoff := wait.Backoff{
+		Cap:      bootstrapBackoffCap,
+		Duration: bootstrapBackoffInitial,
+		Factor:   2,
+		Jitter:   0.1,
+		Steps:    math.MaxInt32,
+	}
+	deadline := time.Now().Add(timeout)
+
+	for {
+		_, err := k8sClient.Discovery().ServerVersion()
+		if err == nil {
+			return nil
+		}
+
+		if time.Now().After(deadline) {
+			return microerror.Maskf(unreachableAddressError, "api server not reachable within %s: %s", timeout, err)
+		}
+
+		// The last attempt is made at the deadline, so that the backoff does
+		// not extend the timeout.
+		d := backoff.Step()
+		if remaining := time.Until(deadline); d > remaining {
+			d = remaining
+		}
+		_ = logger.LogCtx(ctx, "level", "warning", "message", fmt.Sprintf("api server not reachable, retrying in %s", d.Round(time.Millisecond)), "reason", err.Error())
+
+		timer := time.NewTimer(d)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return microerror.Maskf(unreachableAddressError, "api server not reachable before context was done: %s", err)
+		}
+	}
+}
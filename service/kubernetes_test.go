@@ -0,0 +1,92 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/giantswarm/micrologger/microloggertest"
+	"github.com/spf13/viper"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+
+	"github.com/giantswarm/shutdown-deferrer/flag"
+)
+
+func Test_newRESTConfig(t *testing.T) {
+	f := flag.New()
+
+	testCases := []struct {
+		name         string
+		settings     map[string]interface{}
+		errorMatcher func(error) bool
+	}{
+		{
+			name: "case 0: should create config for address",
+			settings: map[string]interface{}{
+				f.Service.Kubernetes.Address: "https://127.0.0.1:6443",
+			},
+			errorMatcher: nil,
+		},
+		{
+			name: "case 1: should fail with missing TLS file",
+			settings: map[string]interface{}{
+				f.Service.Kubernetes.Address:    "https://127.0.0.1:6443",
+				f.Service.Kubernetes.TLS.CAFile: "/does/not/exist/ca.pem",
+			},
+			errorMatcher: IsTLSFileNotFound,
+		},
+		{
+			name: "case 2: should fail with invalid kubeconfig",
+			settings: map[string]interface{}{
+				f.Service.Kubernetes.KubeConfig: "{not yaml",
+			},
+			errorMatcher: IsInvalidKubeConfig,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			v := viper.New()
+			for k, s := range tc.settings {
+				v.Set(k, s)
+			}
+
+			_, err := newRESTConfig(microloggertest.New(), f, v)
+
+			switch {
+			case err == nil && tc.errorMatcher == nil:
+				// correct; carry on
+			case err != nil && tc.errorMatcher == nil:
+				t.Fatalf("error == %#v, want nil", err)
+			case err == nil && tc.errorMatcher != nil:
+				t.Fatalf("error == nil, want non-nil")
+			case !tc.errorMatcher(err):
+				t.Fatalf("error == %#v, want matching", err)
+			}
+		})
+	}
+}
+
+func Test_waitForAPIServer(t *testing.T) {
+	// Nothing listens on port 1, so that requests fail right away.
+	k8sClient, err := kubernetes.NewForConfig(&rest.Config{Host: "http://127.0.0.1:1"})
+	if err != nil {
+		t.Fatalf("error == %#v, want nil", err)
+	}
+
+	timeout := 1500 * time.Millisecond
+
+	start := time.Now()
+	err = waitForAPIServer(context.Background(), microloggertest.New(), k8sClient, timeout)
+	if !IsUnreachableAddress(err) {
+		t.Fatalf("error == %#v, want unreachableAddressError", err)
+	}
+
+	// Without bounding the backoff by the timeout the second wait of about 2
+	// seconds would be slept in full.
+	elapsed := time.Since(start)
+	if elapsed > timeout+500*time.Millisecond {
+		t.Fatalf("waited %s, want at most %s", elapsed, timeout+500*time.Millisecond)
+	}
+}
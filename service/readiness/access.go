@@ -0,0 +1,160 @@
+package readiness
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/giantswarm/microendpoint/service/healthz"
+	"github.com/giantswarm/microerror"
+	"github.com/giantswarm/micrologger"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/giantswarm/shutdown-deferrer/service/deferrer"
+)
+
+const (
+	// AccessDescription describes which functionality the access check
+	// implements.
+	AccessDescription = "Ensure the service account is allowed to access the Kubernetes API resources required by the providers."
+	// AccessName is the identifier of the access check.
+	AccessName = "access"
+	// AccessSuccessMessage is the message returned in case the access check did
+	// not fail.
+	AccessSuccessMessage = "Access granted."
+)
+
+const (
+	// DefaultAccessCacheDuration is the time a result of the access check is
+	// reused before the permissions are reviewed again, so that frequent probes
+	// do not flood the API server with SelfSubjectAccessReviews.
+	DefaultAccessCacheDuration = 1 * time.Minute
+)
+
+// AccessCheckConfig represents the configuration used to create an access
+// check.
+type AccessCheckConfig struct {
+	K8sClient kubernetes.Interface
+	Logger    micrologger.Logger
+
+	// CacheDuration is the time a check result is reused. It defaults to
+	// DefaultAccessCacheDuration.
+	CacheDuration time.Duration
+	Permissions   []deferrer.Permission
+}
+
+// AccessCheck reviews the permissions required by the deferrer providers
+// using SelfSubjectAccessReviews.
+type AccessCheck struct {
+	k8sClient kubernetes.Interface
+	logger    micrologger.Logger
+
+	cacheDuration time.Duration
+	permissions   []deferrer.Permission
+
+	checked time.Time
+	denied  []deferrer.Permission
+	mutex   sync.Mutex
+}
+
+// NewAccessCheck creates a new configured access check.
+func NewAccessCheck(config AccessCheckConfig) (*AccessCheck, error) {
+	if config.K8sClient == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.K8sClient must not be empty", config)
+	}
+	if config.Logger == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Logger must not be empty", config)
+	}
+
+	if config.CacheDuration == 0 {
+		config.CacheDuration = DefaultAccessCacheDuration
+	}
+
+	c := &AccessCheck{
+		k8sClient: config.K8sClient,
+		logger:    config.Logger,
+
+		cacheDuration: config.CacheDuration,
+		permissions:   config.Permissions,
+	}
+
+	return c, nil
+}
+
+func (c *AccessCheck) GetHealthz(ctx context.Context) (healthz.Response, error) {
+	r := healthz.Response{
+		Description: AccessDescription,
+		Failed:      false,
+		Message:     AccessSuccessMessage,
+		Name:        AccessName,
+	}
+
+	denied, err := c.Denied(ctx)
+	if err != nil {
+		r.Failed = true
+		r.Message = fmt.Sprintf("failed to review access: %s", err)
+	} else if len(denied) != 0 {
+		var s []string
+		for _, p := range denied {
+			s = append(s, p.String())
+		}
+
+		r.Failed = true
+		r.Message = fmt.Sprintf("access denied: %s", strings.Join(s, ", "))
+	}
+
+	return r, nil
+}
+
+// Denied returns the configured permissions the service account is not
+// granted. Results are cached for the configured cache duration. Errors are
+// not cached.
+func (c *AccessCheck) Denied(ctx context.Context) ([]deferrer.Permission, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if !c.checked.IsZero() && time.Since(c.checked) < c.cacheDuration {
+		return c.denied, nil
+	}
+
+	var denied []deferrer.Permission
+	for _, p := range c.permissions {
+		allowed, err := c.review(p)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+
+		if !allowed {
+			_ = c.logger.LogCtx(ctx, "level", "warning", "message", fmt.Sprintf("service account is not allowed to %s", p))
+			denied = append(denied, p)
+		}
+	}
+
+	c.checked = time.Now()
+	c.denied = denied
+
+	return denied, nil
+}
+
+func (c *AccessCheck) review(p deferrer.Permission) (bool, error) {
+	review := &authorizationv1.SelfSubjectAccessReview{
+		Spec: authorizationv1.SelfSubjectAccessReviewSpec{
+			ResourceAttributes: &authorizationv1.ResourceAttributes{
+				Group:     p.Group,
+				Namespace: p.Namespace,
+				Resource:  p.Resource,
+				Verb:      p.Verb,
+			},
+		},
+	}
+
+	review, err := c.k8sClient.AuthorizationV1().SelfSubjectAccessReviews().Create(review)
+	if err != nil {
+		return false, microerror.Mask(err)
+	}
+
+	return review.Status.Allowed, nil
+}
@@ -0,0 +1,100 @@
+package readiness
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/giantswarm/micrologger/microloggertest"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+
+	"github.com/giantswarm/shutdown-deferrer/service/deferrer"
+)
+
+func Test_AccessCheck_GetHealthz(t *testing.T) {
+	permissions := []deferrer.Permission{
+		{Group: "core.giantswarm.io", Namespace: "bar", Resource: "drainerconfigs", Verb: "get"},
+		{Group: "core.giantswarm.io", Namespace: "bar", Resource: "drainerconfigs", Verb: "watch"},
+	}
+
+	testCases := []struct {
+		name           string
+		allowedVerbs   map[string]bool
+		reviewErr      error
+		expectedFailed bool
+	}{
+		{
+			name:           "case 0: should not fail when all permissions are granted",
+			allowedVerbs:   map[string]bool{"get": true, "watch": true},
+			expectedFailed: false,
+		},
+		{
+			name:           "case 1: should fail when a permission is denied",
+			allowedVerbs:   map[string]bool{"get": true},
+			expectedFailed: true,
+		},
+		{
+			name:           "case 2: should fail when the access review fails",
+			reviewErr:      errors.New("connection refused"),
+			expectedFailed: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var reviews int
+
+			k8sClient := fake.NewSimpleClientset()
+			k8sClient.PrependReactor("create", "selfsubjectaccessreviews", func(action k8stesting.Action) (bool, runtime.Object, error) {
+				reviews++
+
+				// The fake client type asserts the returned object even in
+				// case of errors.
+				if tc.reviewErr != nil {
+					return true, &authorizationv1.SelfSubjectAccessReview{}, tc.reviewErr
+				}
+
+				review := action.(k8stesting.CreateAction).GetObject().(*authorizationv1.SelfSubjectAccessReview)
+				review.Status.Allowed = tc.allowedVerbs[review.Spec.ResourceAttributes.Verb]
+
+				return true, review, nil
+			})
+
+			c := AccessCheckConfig{
+				K8sClient: k8sClient,
+				Logger:    microloggertest.New(),
+
+				Permissions: permissions,
+			}
+
+			accessCheck, err := NewAccessCheck(c)
+			if err != nil {
+				t.Fatalf("error == %#v, want nil", err)
+			}
+
+			r, err := accessCheck.GetHealthz(context.Background())
+			if err != nil {
+				t.Fatalf("error == %#v, want nil", err)
+			}
+			if r.Failed != tc.expectedFailed {
+				t.Fatalf("Failed == %t, want %t (%s)", r.Failed, tc.expectedFailed, r.Message)
+			}
+
+			// Successful reviews are cached, failed reviews are retried.
+			reviewsBefore := reviews
+			_, err = accessCheck.GetHealthz(context.Background())
+			if err != nil {
+				t.Fatalf("error == %#v, want nil", err)
+			}
+			if tc.reviewErr == nil && reviews != reviewsBefore {
+				t.Fatalf("reviews == %d, want %d", reviews, reviewsBefore)
+			}
+			if tc.reviewErr != nil && reviews == reviewsBefore {
+				t.Fatalf("reviews == %d, want more than %d", reviews, reviewsBefore)
+			}
+		})
+	}
+}
@@ -0,0 +1,65 @@
+package readiness
+
+import (
+	"context"
+
+	"github.com/giantswarm/microendpoint/service/healthz"
+	"github.com/giantswarm/microerror"
+
+	"github.com/giantswarm/shutdown-deferrer/service/deferrer"
+)
+
+const (
+	// ProvidersDescription describes which functionality the providers check
+	// implements.
+	ProvidersDescription = "Ensure the deferrer providers are able to decide."
+	// ProvidersName is the identifier of the providers check.
+	ProvidersName = "providers"
+	// ProvidersSuccessMessage is the message returned in case the providers
+	// check did not fail.
+	ProvidersSuccessMessage = "Providers ready."
+)
+
+// ProvidersCheckConfig represents the configuration used to create a providers
+// check.
+type ProvidersCheckConfig struct {
+	Deferrer *deferrer.Service
+}
+
+// ProvidersCheck reports whether the informer caches of the deferrer providers
+// are synced and their last lookups from the API server succeeded.
+type ProvidersCheck struct {
+	deferrer *deferrer.Service
+}
+
+// NewProvidersCheck creates a new configured providers check.
+func NewProvidersCheck(config ProvidersCheckConfig) (*ProvidersCheck, error) {
+	if config.Deferrer == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Deferrer must not be empty", config)
+	}
+
+	c := &ProvidersCheck{
+		deferrer: config.Deferrer,
+	}
+
+	return c, nil
+}
+
+func (c *ProvidersCheck) GetHealthz(ctx context.Context) (healthz.Response, error) {
+	r := healthz.Response{
+		Description: ProvidersDescription,
+		Failed:      false,
+		Message:     ProvidersSuccessMessage,
+		Name:        ProvidersName,
+	}
+
+	err := c.deferrer.Ready(ctx)
+	if deferrer.IsNotReady(err) {
+		r.Failed = true
+		r.Message = err.Error()
+	} else if err != nil {
+		return healthz.Response{}, microerror.Mask(err)
+	}
+
+	return r, nil
+}
@@ -1,18 +1,26 @@
 package service
 
 import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
 	"sync"
 
 	"github.com/giantswarm/apiextensions/pkg/clientset/versioned"
-	"github.com/giantswarm/k8sclient/k8srestconfig"
+	"github.com/giantswarm/microendpoint/service/healthz"
 	"github.com/giantswarm/microendpoint/service/version"
 	"github.com/giantswarm/microerror"
 	"github.com/giantswarm/micrologger"
 	"github.com/spf13/viper"
-	"k8s.io/client-go/rest"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
 
 	"github.com/giantswarm/shutdown-deferrer/flag"
+	"github.com/giantswarm/shutdown-deferrer/service/audit"
 	"github.com/giantswarm/shutdown-deferrer/service/deferrer"
+	"github.com/giantswarm/shutdown-deferrer/service/identity"
+	"github.com/giantswarm/shutdown-deferrer/service/readiness"
 )
 
 // Config represents the configuration used to create a new service.
@@ -31,10 +39,18 @@ type Config struct {
 
 // Service is a type providing implementation of microkit service interface.
 type Service struct {
+	// Audit writes the audit trail of defer queries.
+	Audit    *audit.Logger
 	Deferrer *deferrer.Service
-	Version  *version.Service
+	// K8sClient is the Kubernetes client shared with the server, e.g. for
+	// reviewing tokens of clients.
+	K8sClient kubernetes.Interface
+	// Readiness are the checks backing the readyz endpoint.
+	Readiness []healthz.Service
+	Version   *version.Service
 
 	bootOnce sync.Once
+	logger   micrologger.Logger
 }
 
 // New creates a new service with given configuration.
@@ -52,37 +68,248 @@ func New(config Config) (*Service, error) {
 
 	var err error
 
-	var restConfig *rest.Config
-	{
-		c := k8srestconfig.Config{
+	multiTenant := config.Viper.GetBool(config.Flag.Service.Deferrer.MultiTenant)
+
+	// The POD identity is resolved once at startup so that misconfiguration
+	// fails the POD at boot instead of on the first preStop call. In
+	// multi-tenant mode decisions are made for the PODs given in requests.
+	var podIdentity identity.Identity
+	if !multiTenant {
+		c := identity.Config{
 			Logger: config.Logger,
 
-			Address:    config.Viper.GetString(config.Flag.Service.Kubernetes.Address),
-			InCluster:  config.Viper.GetBool(config.Flag.Service.Kubernetes.InCluster),
-			KubeConfig: config.Viper.GetString(config.Flag.Service.Kubernetes.KubeConfig),
-			TLS: k8srestconfig.ConfigTLS{
-				CAFile:  config.Viper.GetString(config.Flag.Service.Kubernetes.TLS.CAFile),
-				CrtFile: config.Viper.GetString(config.Flag.Service.Kubernetes.TLS.CrtFile),
-				KeyFile: config.Viper.GetString(config.Flag.Service.Kubernetes.TLS.KeyFile),
-			},
+			Sources: config.Viper.GetStringSlice(config.Flag.Service.Deferrer.Pod.Sources),
+
+			Name:      config.Viper.GetString(config.Flag.Service.Deferrer.Pod.Name),
+			Namespace: config.Viper.GetString(config.Flag.Service.Deferrer.Pod.Namespace),
+
+			DownwardAPIDir:              config.Viper.GetString(config.Flag.Service.Deferrer.Pod.DownwardAPIDir),
+			ServiceAccountNamespaceFile: config.Viper.GetString(config.Flag.Service.Deferrer.Pod.NamespaceFile),
 		}
 
-		restConfig, err = k8srestconfig.New(c)
+		podIdentity, err = identity.Resolve(context.Background(), c)
 		if err != nil {
-			panic(err)
+			return nil, microerror.Mask(err)
 		}
 	}
 
+	providerNames := config.Viper.GetStringSlice(config.Flag.Service.Deferrer.Provider.Names)
+
+	// The node name is resolved once at startup as well. It is required by
+	// the providers deciding based on the node the POD is running on.
+	nodeName := os.Getenv(deferrer.EnvKeyMyNodeName)
+	for _, name := range providerNames {
+		if !multiTenant && nodeName == "" && (name == deferrer.ProviderNodeDrain || name == deferrer.ProviderPodDisruptionBudget) {
+			return nil, microerror.Maskf(invalidConfigError, "provider %q requires the node name - make sure $%s is set", name, deferrer.EnvKeyMyNodeName)
+		}
+	}
+
+	restConfig, err := newRESTConfig(config.Logger, config.Flag, config.Viper)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
 	g8sClient, err := versioned.NewForConfig(restConfig)
 	if err != nil {
-		panic(err)
+		return nil, microerror.Maskf(invalidKubeConfigError, "%s", err)
+	}
+
+	k8sClient, err := kubernetes.NewForConfig(restConfig)
+	if err != nil {
+		return nil, microerror.Maskf(invalidKubeConfigError, "%s", err)
+	}
+
+	{
+		timeout := config.Viper.GetDuration(config.Flag.Service.Bootstrap.Timeout)
+
+		err = waitForAPIServer(context.Background(), config.Logger, k8sClient, timeout)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+	}
+
+	var providers []deferrer.Provider
+	{
+		for _, name := range providerNames {
+			// Other providers decide based on the node the deferrer is
+			// running on, which is not the node of the PODs decided for in
+			// multi-tenant mode.
+			if multiTenant && name != deferrer.ProviderDrainerConfig {
+				return nil, microerror.Maskf(invalidConfigError, "provider %q is not supported in multi-tenant mode", name)
+			}
+
+			switch name {
+			case deferrer.ProviderDrainerConfig:
+				c := deferrer.DrainerConfigProviderConfig{
+					G8sClient: g8sClient,
+					Logger:    config.Logger,
+
+					Target: deferrer.DrainerConfigTarget{
+						Name:          config.Viper.GetString(config.Flag.Service.Deferrer.DrainerConfig.Name),
+						Namespace:     config.Viper.GetString(config.Flag.Service.Deferrer.DrainerConfig.Namespace),
+						LabelSelector: config.Viper.GetString(config.Flag.Service.Deferrer.DrainerConfig.LabelSelector),
+					},
+				}
+
+				p, err := deferrer.NewDrainerConfigProvider(c)
+				if err != nil {
+					return nil, microerror.Mask(err)
+				}
+
+				providers = append(providers, p)
+			case deferrer.ProviderNodeDrain:
+				c := deferrer.NodeDrainProviderConfig{
+					K8sClient: k8sClient,
+					Logger:    config.Logger,
+
+					NodeName: nodeName,
+				}
+
+				p, err := deferrer.NewNodeDrainProvider(c)
+				if err != nil {
+					return nil, microerror.Mask(err)
+				}
+
+				providers = append(providers, p)
+			case deferrer.ProviderPodDisruptionBudget:
+				c := deferrer.PodDisruptionBudgetProviderConfig{
+					K8sClient: k8sClient,
+					Logger:    config.Logger,
+
+					NodeName: nodeName,
+				}
+
+				p, err := deferrer.NewPodDisruptionBudgetProvider(c)
+				if err != nil {
+					return nil, microerror.Mask(err)
+				}
+
+				providers = append(providers, p)
+			default:
+				return nil, microerror.Maskf(invalidConfigError, "unknown provider %q", name)
+			}
+		}
+	}
+
+	// The POD annotations and overrides are read from the own POD or its
+	// DrainerConfig, which do not exist in multi-tenant mode. Both are
+	// disabled by default, so that no permissions on PODs are required.
+	overrideTarget := config.Viper.GetString(config.Flag.Service.Deferrer.Override.Target)
+	readPodAnnotations := config.Viper.GetBool(config.Flag.Service.Deferrer.Annotations.Pod)
+
+	var podStore *deferrer.PodOverrideStore
+	if !multiTenant && (overrideTarget == deferrer.OverrideTargetPod || readPodAnnotations) {
+		c := deferrer.PodOverrideStoreConfig{
+			K8sClient: k8sClient,
+			Logger:    config.Logger,
+		}
+
+		podStore, err = deferrer.NewPodOverrideStore(c)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+	}
+
+	// The store is assigned only when it is created, so that the interface is
+	// not a typed nil.
+	var podAnnotations deferrer.PodAnnotationSource
+	if podStore != nil && readPodAnnotations {
+		podAnnotations = podStore
+	}
+
+	var overrideStore deferrer.OverrideStore
+	if !multiTenant {
+		switch overrideTarget {
+		case "":
+			// Overrides are disabled.
+		case deferrer.OverrideTargetDrainerConfig:
+			// The DrainerConfig provider is reused when it is configured,
+			// so that a single informer watches the DrainerConfig.
+			var provider *deferrer.DrainerConfigProvider
+			for _, p := range providers {
+				provider, _ = p.(*deferrer.DrainerConfigProvider)
+				if provider != nil {
+					break
+				}
+			}
+			if provider == nil {
+				c := deferrer.DrainerConfigProviderConfig{
+					G8sClient: g8sClient,
+					Logger:    config.Logger,
+
+					Target: deferrer.DrainerConfigTarget{
+						Name:          config.Viper.GetString(config.Flag.Service.Deferrer.DrainerConfig.Name),
+						Namespace:     config.Viper.GetString(config.Flag.Service.Deferrer.DrainerConfig.Namespace),
+						LabelSelector: config.Viper.GetString(config.Flag.Service.Deferrer.DrainerConfig.LabelSelector),
+					},
+				}
+
+				provider, err = deferrer.NewDrainerConfigProvider(c)
+				if err != nil {
+					return nil, microerror.Mask(err)
+				}
+			}
+
+			c := deferrer.DrainerConfigOverrideStoreConfig{
+				Logger:   config.Logger,
+				Provider: provider,
+			}
+
+			overrideStore, err = deferrer.NewDrainerConfigOverrideStore(c)
+			if err != nil {
+				return nil, microerror.Mask(err)
+			}
+		case deferrer.OverrideTargetPod:
+			overrideStore = podStore
+		default:
+			return nil, microerror.Maskf(invalidConfigError, "unknown override target %q", overrideTarget)
+		}
+	}
+
+	// In multi-tenant mode the PODs decided for are cached, so that they are
+	// not requested from the API server for every decision or Event.
+	var podInformer cache.SharedIndexInformer
+	if multiTenant {
+		podInformer = deferrer.NewPodInformer(k8sClient)
+	}
+
+	var eventRecorder deferrer.EventRecorder
+	if config.Viper.GetBool(config.Flag.Service.Deferrer.Events.Enabled) {
+		c := deferrer.KubernetesEventRecorderConfig{
+			K8sClient: k8sClient,
+			Logger:    config.Logger,
+
+			DrainerConfig: config.Viper.GetBool(config.Flag.Service.Deferrer.Events.DrainerConfig),
+			NodeName:      nodeName,
+			PodInformer:   podInformer,
+		}
+
+		eventRecorder, err = deferrer.NewKubernetesEventRecorder(c)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
 	}
 
 	var deferrerService *deferrer.Service
 	{
 		c := deferrer.Config{
-			G8sClient: g8sClient,
-			Logger:    config.Logger,
+			EventRecorder: eventRecorder,
+			K8sClient:     k8sClient,
+			Logger:        config.Logger,
+			MultiTenant:   multiTenant,
+			Overrides:     overrideStore,
+			Pod: deferrer.Pod{
+				Name:      podIdentity.Name,
+				Namespace: podIdentity.Namespace,
+			},
+			PodAnnotations: podAnnotations,
+			PodInformer:    podInformer,
+			Providers:      providers,
+
+			Combination:      config.Viper.GetString(config.Flag.Service.Deferrer.Provider.Combination),
+			Deadline:         config.Viper.GetDuration(config.Flag.Service.Deferrer.Deadline),
+			FailurePolicy:    config.Viper.GetString(config.Flag.Service.Deferrer.Failure.Policy),
+			FailureThreshold: config.Viper.GetInt(config.Flag.Service.Deferrer.Failure.Threshold),
 		}
 
 		deferrerService, err = deferrer.New(c)
@@ -91,6 +318,60 @@ func New(config Config) (*Service, error) {
 		}
 	}
 
+	var readinessChecks []healthz.Service
+	{
+		c := readiness.ProvidersCheckConfig{
+			Deferrer: deferrerService,
+		}
+
+		providersCheck, err := readiness.NewProvidersCheck(c)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+
+		readinessChecks = append(readinessChecks, providersCheck)
+	}
+	{
+		c := readiness.AccessCheckConfig{
+			K8sClient: k8sClient,
+			Logger:    config.Logger,
+
+			Permissions: deferrerService.Permissions(),
+		}
+
+		accessCheck, err := readiness.NewAccessCheck(c)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+
+		// RBAC mistakes would otherwise only show up as failed lookups when
+		// the POD is terminated, so the permissions are reviewed right away.
+		if config.Viper.GetBool(config.Flag.Service.Deferrer.Access.Check) {
+			required := config.Viper.GetBool(config.Flag.Service.Deferrer.Access.Required)
+
+			err = checkAccess(context.Background(), config.Logger, accessCheck, required)
+			if err != nil {
+				return nil, microerror.Mask(err)
+			}
+		}
+
+		readinessChecks = append(readinessChecks, accessCheck)
+	}
+
+	var auditLogger *audit.Logger
+	{
+		c := audit.Config{
+			Path:       config.Viper.GetString(config.Flag.Service.Audit.Path),
+			MaxBackups: config.Viper.GetInt(config.Flag.Service.Audit.MaxBackups),
+			MaxSize:    config.Viper.GetInt64(config.Flag.Service.Audit.MaxSize),
+		}
+
+		auditLogger, err = audit.New(c)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+	}
+
 	var versionService *version.Service
 	{
 		c := version.Config{
@@ -108,10 +389,14 @@ func New(config Config) (*Service, error) {
 	}
 
 	s := &Service{
-		Deferrer: deferrerService,
-		Version:  versionService,
+		Audit:     auditLogger,
+		Deferrer:  deferrerService,
+		K8sClient: k8sClient,
+		Readiness: readinessChecks,
+		Version:   versionService,
 
 		bootOnce: sync.Once{},
+		logger:   config.Logger,
 	}
 
 	return s, nil
@@ -120,5 +405,48 @@ func New(config Config) (*Service, error) {
 // Boot starts top level service implementation.
 func (s *Service) Boot() {
 	s.bootOnce.Do(func() {
+		ctx := context.Background()
+
+		err := s.Deferrer.Boot(ctx)
+		if err != nil {
+			// The deferrer boots all its components regardless of a single
+			// failure and reports failures through its readiness, so we only
+			// log the error here.
+			_ = s.logger.LogCtx(ctx, "level", "error", "message", "failed to boot deferrer service", "stack", fmt.Sprintf("%#v", err))
+		}
 	})
 }
+
+// checkAccess reviews the permissions required by the providers and logs a
+// summary. When required is true an accessDeniedError is returned in case any
+// permission is denied or the review fails.
+func checkAccess(ctx context.Context, logger micrologger.Logger, accessCheck *readiness.AccessCheck, required bool) error {
+	denied, err := accessCheck.Denied(ctx)
+	if err != nil {
+		_ = logger.LogCtx(ctx, "level", "error", "message", "failed to review kubernetes api permissions required by providers", "stack", fmt.Sprintf("%#v", err))
+
+		if required {
+			return microerror.Maskf(accessDeniedError, "failed to review kubernetes api permissions: %s", err)
+		}
+
+		return nil
+	}
+
+	if len(denied) == 0 {
+		_ = logger.LogCtx(ctx, "level", "info", "message", "service account is granted all kubernetes api permissions required by providers")
+		return nil
+	}
+
+	var s []string
+	for _, p := range denied {
+		s = append(s, p.String())
+	}
+
+	_ = logger.LogCtx(ctx, "level", "error", "message", fmt.Sprintf("service account is denied kubernetes api permissions required by providers: %s", strings.Join(s, ", ")))
+
+	if required {
+		return microerror.Maskf(accessDeniedError, "service account is denied %s", strings.Join(s, ", "))
+	}
+
+	return nil
+}